@@ -0,0 +1,49 @@
+// Comando gotelemd-server expõe a conversão por HTTP (ver pkg/server).
+//
+//	gotelemd-server -addr :8080 -config gotelemd.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sshturbo/GoTeleMD/pkg/server"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	configPath := flag.String("config", "", "JSON or YAML config file, applied before GOTELEMD_* env vars")
+	maxBodySize := flag.Int64("max-body-size", server.DefaultMaxBodySize, "maximum request body size in bytes")
+	shutdownTimeout := flag.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "time to wait for in-flight requests on shutdown")
+	flag.Parse()
+
+	config, err := types.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotelemd-server: %v\n", err)
+		os.Exit(1)
+	}
+
+	level := slog.LevelInfo
+	if config.EnableDebugLogs {
+		level = slog.LevelDebug
+	}
+	config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := server.New(config,
+		server.WithMaxBodySize(*maxBodySize),
+		server.WithShutdownTimeout(*shutdownTimeout),
+	)
+	if err := srv.ListenAndServe(ctx, *addr); err != nil {
+		fmt.Fprintf(os.Stderr, "gotelemd-server: %v\n", err)
+		os.Exit(1)
+	}
+}
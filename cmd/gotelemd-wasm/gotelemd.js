@@ -0,0 +1,29 @@
+// Carrega o gotelemd.wasm e devolve a API de conversão. Requer o wasm_exec.js
+// da mesma versão do Go usada na compilação:
+//
+//   cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+//   <script src="wasm_exec.js"></script>
+//   <script type="module">
+//     import { loadGoTeleMD } from "./gotelemd.js";
+//     const gotelemd = await loadGoTeleMD("gotelemd.wasm");
+//     const response = gotelemd.convert("# Olá", { safety_level: 2 });
+//   </script>
+export async function loadGoTeleMD(url = "gotelemd.wasm") {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  // run só termina quando o programa Go sair; a API já está registrada
+  // quando ele para no select do main
+  go.run(instance);
+
+  const api = globalThis.gotelemd;
+  return {
+    convert(markdown, options) {
+      const result = api.convert(markdown, options);
+      if (result instanceof Error) {
+        throw result;
+      }
+      return result;
+    },
+  };
+}
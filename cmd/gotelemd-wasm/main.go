@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+// Comando gotelemd-wasm compila o conversor para WebAssembly e o expõe ao
+// JavaScript, para que o navegador use exatamente a mesma conversão do bot.
+//
+//	GOOS=js GOARCH=wasm go build -o gotelemd.wasm ./cmd/gotelemd-wasm
+//
+// Depois de iniciado, o módulo define globalThis.gotelemd com a função
+// convert(markdown, options). options é um objeto com as mesmas chaves dos
+// arquivos de configuração (ou uma string JSON) e pode ser omitido. O retorno
+// é o MessageResponse como objeto JavaScript, ou um Error em caso de falha;
+// o gotelemd.js carrega o módulo e lança esses erros.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/sshturbo/GoTeleMD"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("convert", js.FuncOf(convert))
+	js.Global().Set("gotelemd", api)
+
+	// Mantém o programa vivo para atender às chamadas do JavaScript
+	select {}
+}
+
+func convert(this js.Value, args []js.Value) (result any) {
+	// Um panic encerraria o módulo inteiro; vira um erro para quem chamou
+	defer func() {
+		if r := recover(); r != nil {
+			result = throw(fmt.Errorf("conversion panicked: %v", r))
+		}
+	}()
+
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return throw(errors.New("convert: markdown must be a string"))
+	}
+	markdown := args[0].String()
+
+	config := types.DefaultConfig()
+	if len(args) > 1 {
+		if err := applyOptions(config, args[1]); err != nil {
+			return throw(err)
+		}
+	}
+	converter, err := GoTeleMD.NewConverterFromConfig(config)
+	if err != nil {
+		return throw(err)
+	}
+	response, err := converter.Convert(markdown)
+	if err != nil {
+		return throw(err)
+	}
+	return toJS(response)
+}
+
+// applyOptions aplica as opções recebidas do JavaScript, como objeto ou como
+// string JSON
+func applyOptions(config *types.Config, options js.Value) error {
+	var raw string
+	switch options.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		return nil
+	case js.TypeString:
+		raw = options.String()
+	case js.TypeObject:
+		raw = js.Global().Get("JSON").Call("stringify", options).String()
+	default:
+		return fmt.Errorf("convert: options must be an object, got %s", options.Type())
+	}
+
+	return config.LoadJSON([]byte(raw))
+}
+
+// toJS converte o valor para um objeto JavaScript passando pelo JSON, para
+// manter os mesmos nomes de campos da API HTTP
+func toJS(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return throw(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
+
+// throw devolve o erro como um Error do JavaScript. Funções criadas com
+// js.FuncOf não podem lançar exceções, então quem lança é o gotelemd.js.
+func throw(err error) any {
+	return js.Global().Get("Error").New(err.Error())
+}
@@ -0,0 +1,342 @@
+// Comando gotelemd converte Markdown para o formato do Telegram a partir da
+// linha de comando. Lê de um arquivo ou da entrada padrão e escreve as partes
+// convertidas na saída padrão.
+//
+//	gotelemd [flags] [arquivo.md]
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// flags guarda os valores da linha de comando. Só as flags informadas
+// explicitamente viram opções, para não sobrescrever o arquivo de
+// configuração e o ambiente com valores padrão.
+type flags struct {
+	configPath           string
+	safetyLevel          int
+	blockSafetyLevels    string
+	maxLength            int
+	alignTables          bool
+	ignoreTableSeparator bool
+	outputMode           string
+	strictLineBreaks     bool
+	preserveEmptyLines   bool
+	escapeChars          string
+	linkSchemes          string
+	onDisallowedLink     string
+	linkPreview          string
+	linkPreviewURL       string
+	buttons              bool
+	workers              int
+	queueSize            int
+	concurrentParts      int
+	debug                bool
+	stats                bool
+	chatID               int64
+	threadID             int
+	replyTo              int
+	disableNotification  bool
+	protectContent       bool
+
+	format    string
+	delimiter string
+	quiet     bool
+}
+
+func newFlagSet(f *flags, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("gotelemd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gotelemd [flags] [file.md]")
+		fmt.Fprintln(stderr, "Converts Markdown to Telegram MarkdownV2. Reads stdin when no file (or -) is given.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&f.configPath, "config", "", "JSON or YAML config file, applied before GOTELEMD_* env vars and flags")
+	fs.IntVar(&f.safetyLevel, "safety", 1, "safety level: 0 (none), 1 (basic) or 2 (strict)")
+	fs.StringVar(&f.blockSafetyLevels, "block-safety", "", "per block type safety levels, e.g. quote=2,table=2")
+	fs.IntVar(&f.maxLength, "max-length", 4096, "maximum length of each message part")
+	fs.BoolVar(&f.alignTables, "align-tables", true, "pad table columns to the same width")
+	fs.BoolVar(&f.ignoreTableSeparator, "ignore-table-separator", false, "ignore the alignment row of tables")
+	fs.StringVar(&f.outputMode, "output-mode", "markdownv2", "output mode: markdownv2 or plain")
+	fs.BoolVar(&f.strictLineBreaks, "strict-line-breaks", true, "keep every line break; false joins soft-wrapped lines")
+	fs.BoolVar(&f.preserveEmptyLines, "preserve-empty-lines", true, "keep repeated blank lines; false collapses them")
+	fs.StringVar(&f.escapeChars, "escape-chars", "", "extra characters to escape in text, e.g. @,$")
+	fs.StringVar(&f.linkSchemes, "link-schemes", "", "allowed link URL schemes, e.g. https,tg")
+	fs.StringVar(&f.onDisallowedLink, "on-disallowed-link", "", "what to do with disallowed links: keep_text, drop or show_url_as_code")
+	fs.StringVar(&f.linkPreview, "link-preview", "default", "link preview per part: default, disabled, first_link or url (json format only)")
+	fs.StringVar(&f.linkPreviewURL, "link-preview-url", "", "URL to preview, implies -link-preview url (json format only)")
+	fs.BoolVar(&f.buttons, "buttons", false, "turn a trailing paragraph of button links into an inline keyboard (json format only)")
+	fs.IntVar(&f.workers, "workers", 4, "number of render workers (0 = one per CPU)")
+	fs.IntVar(&f.queueSize, "queue-size", 32, "render worker queue size")
+	fs.IntVar(&f.concurrentParts, "concurrent-parts", 8, "maximum parts rendered at the same time")
+	fs.BoolVar(&f.debug, "debug", false, "write debug logs to stderr")
+	fs.BoolVar(&f.stats, "stats", false, "include conversion statistics (json format only)")
+	fs.Int64Var(&f.chatID, "chat-id", 0, "chat_id of the sendMessage payload of each part (json format only)")
+	fs.IntVar(&f.threadID, "thread-id", 0, "message_thread_id of the payload (json format only)")
+	fs.IntVar(&f.replyTo, "reply-to", 0, "message the first part replies to (json format only)")
+	fs.BoolVar(&f.disableNotification, "disable-notification", false, "send the parts silently (json format only)")
+	fs.BoolVar(&f.protectContent, "protect-content", false, "protect the parts from forwarding and saving (json format only)")
+
+	fs.StringVar(&f.format, "format", "text", "output format: text (parts separated by -delimiter) or json (MessageResponse)")
+	fs.StringVar(&f.delimiter, "delimiter", "\n\n-----\n\n", "delimiter written between parts in text format")
+	fs.BoolVar(&f.quiet, "quiet", false, "do not write diagnostics to stderr in text format")
+	return fs
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var f flags
+	fs := newFlagSet(&f, stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	if f.format != "text" && f.format != "json" {
+		fmt.Fprintf(stderr, "gotelemd: unknown format %q (expected text or json)\n", f.format)
+		return 2
+	}
+	if f.format == "text" {
+		if name := jsonOnlyFlag(fs); name != "" {
+			fmt.Fprintf(stderr, "gotelemd: -%s only applies to -format json\n", name)
+			return 2
+		}
+	}
+
+	options, err := f.options(fs)
+	if err != nil {
+		fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+		return 2
+	}
+	config, err := types.LoadConfig(f.configPath, options...)
+	if err != nil {
+		fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+		return 1
+	}
+
+	input, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+		return 1
+	}
+
+	converter, err := GoTeleMD.NewConverterFromConfig(config)
+	if err != nil {
+		fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+		return 1
+	}
+	response, err := converter.Convert(string(input))
+	if err != nil {
+		fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+		return 1
+	}
+
+	if f.format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(response); err != nil {
+			fmt.Fprintf(stderr, "gotelemd: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	for i, part := range response.Parts {
+		if i > 0 {
+			io.WriteString(stdout, f.delimiter)
+		}
+		io.WriteString(stdout, part.Content)
+	}
+	io.WriteString(stdout, "\n")
+
+	if !f.quiet {
+		for _, d := range response.Diagnostics {
+			fmt.Fprintf(stderr, "%d:%d: %s: %s (%s)\n", d.Line, d.Column, d.Severity, d.Message, d.Code)
+		}
+	}
+	return 0
+}
+
+// options converte as flags informadas em opções do conversor
+func (f *flags) options(fs *flag.FlagSet) ([]types.Option, error) {
+	var options []types.Option
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch fl.Name {
+		case "safety":
+			options = append(options, types.WithSafetyLevel(f.safetyLevel))
+		case "block-safety":
+			var levels map[types.BlockType]int
+			if levels, err = parseBlockLevels(f.blockSafetyLevels); err == nil {
+				for blockType, level := range levels {
+					options = append(options, types.WithBlockSafetyLevel(blockType, level))
+				}
+			}
+		case "max-length":
+			options = append(options, types.WithMaxMessageLength(f.maxLength))
+		case "align-tables":
+			options = append(options, types.WithTableAlignment(f.alignTables))
+		case "ignore-table-separator":
+			options = append(options, types.WithTableSeparators(f.ignoreTableSeparator))
+		case "output-mode":
+			var mode types.OutputMode
+			if err = mode.UnmarshalText([]byte(f.outputMode)); err == nil {
+				options = append(options, types.WithOutputMode(mode))
+			}
+		case "strict-line-breaks":
+			options = append(options, types.WithStrictLineBreaks(f.strictLineBreaks))
+		case "preserve-empty-lines":
+			options = append(options, types.WithPreserveEmptyLines(f.preserveEmptyLines))
+		case "escape-chars":
+			options = append(options, types.WithCustomEscapeChars(splitList(f.escapeChars)))
+		case "link-schemes":
+			schemes := splitList(f.linkSchemes)
+			options = append(options, func(c *types.Config) {
+				linkPolicy(c).AllowedSchemes = schemes
+			})
+		case "on-disallowed-link":
+			var action types.LinkAction
+			if err = action.UnmarshalText([]byte(f.onDisallowedLink)); err == nil {
+				options = append(options, func(c *types.Config) {
+					linkPolicy(c).OnDisallowed = action
+				})
+			}
+		case "link-preview":
+			var mode types.LinkPreviewMode
+			if err = mode.UnmarshalText([]byte(f.linkPreview)); err == nil {
+				options = append(options, types.WithLinkPreview(mode))
+			}
+		case "link-preview-url":
+			options = append(options, types.WithLinkPreviewURL(f.linkPreviewURL))
+		case "buttons":
+			options = append(options, types.WithButtonExtraction(f.buttons))
+		case "workers":
+			options = append(options, func(c *types.Config) { c.NumWorkers = f.workers })
+		case "queue-size":
+			options = append(options, func(c *types.Config) { c.WorkerQueueSize = f.queueSize })
+		case "concurrent-parts":
+			options = append(options, func(c *types.Config) { c.MaxConcurrentParts = f.concurrentParts })
+		case "debug":
+			options = append(options, types.WithDebugLogs(f.debug))
+		case "stats":
+			options = append(options, types.WithStats(f.stats))
+		case "chat-id":
+			options = append(options, func(c *types.Config) { payload(c).ChatID = f.chatID })
+		case "thread-id":
+			options = append(options, func(c *types.Config) { payload(c).MessageThreadID = f.threadID })
+		case "reply-to":
+			options = append(options, func(c *types.Config) { payload(c).ReplyToMessageID = f.replyTo })
+		case "disable-notification":
+			options = append(options, func(c *types.Config) { payload(c).DisableNotification = f.disableNotification })
+		case "protect-content":
+			options = append(options, func(c *types.Config) { payload(c).ProtectContent = f.protectContent })
+		}
+		if err != nil {
+			err = fmt.Errorf("-%s: %w", fl.Name, err)
+		}
+	})
+	return options, err
+}
+
+// linkPolicy devolve a política de links da configuração, criando a padrão
+// se não houver, para que as flags alterem só os campos informados
+func linkPolicy(c *types.Config) *types.LinkPolicy {
+	if c.LinkPolicy == nil {
+		c.LinkPolicy = types.DefaultLinkPolicy()
+	}
+	return c.LinkPolicy
+}
+
+// payload devolve as opções de payload da configuração, criando-as se não
+// houver, para que as flags alterem só os campos informados
+func payload(c *types.Config) *types.PayloadOptions {
+	if c.Payload == nil {
+		c.Payload = &types.PayloadOptions{}
+	}
+	return c.Payload
+}
+
+// jsonOnlyFlags são as flags cujo efeito só aparece no MessageResponse, e que
+// no formato text seriam descartadas em silêncio
+var jsonOnlyFlags = map[string]bool{
+	"link-preview":         true,
+	"link-preview-url":     true,
+	"buttons":              true,
+	"stats":                true,
+	"chat-id":              true,
+	"thread-id":            true,
+	"reply-to":             true,
+	"disable-notification": true,
+	"protect-content":      true,
+}
+
+// jsonOnlyFlag devolve a primeira flag informada que só vale no formato json
+func jsonOnlyFlag(fs *flag.FlagSet) string {
+	var name string
+	fs.Visit(func(fl *flag.Flag) {
+		if name == "" && jsonOnlyFlags[fl.Name] {
+			name = fl.Name
+		}
+	})
+	return name
+}
+
+func parseBlockLevels(value string) (map[types.BlockType]int, error) {
+	levels := make(map[types.BlockType]int)
+	for _, pair := range splitList(value) {
+		name, level, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected type=level, got %q", pair)
+		}
+		var blockType types.BlockType
+		if err := blockType.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return nil, err
+		}
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimSpace(level), "%d", &n); err != nil {
+			return nil, fmt.Errorf("invalid level %q for %s blocks", level, name)
+		}
+		levels[blockType] = n
+	}
+	return levels, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
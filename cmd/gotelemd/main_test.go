@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestJSONOnlyFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{"text without json flags", []string{"-safety", "2"}, 0, ""},
+		{"stats in text", []string{"-stats"}, 2, "-stats only applies to -format json"},
+		{"chat id in text", []string{"-chat-id", "42"}, 2, "-chat-id only applies to -format json"},
+		{"protect content in text", []string{"-protect-content"}, 2, "-protect-content only applies to -format json"},
+		{"explicit false is still rejected", []string{"-buttons=false"}, 2, "-buttons only applies to -format json"},
+		{"first flag is reported", []string{"-reply-to", "7", "-link-preview", "disabled"}, 2, "-link-preview only applies"},
+		{"json accepts them", []string{"-format", "json", "-stats", "-chat-id", "42", "-disable-notification"}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append(tt.args, "-quiet"), strings.NewReader("**oi**"), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("run(%q) = %d, want %d (stderr %q)", tt.args, code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestJSONOnlyFlagsMatchHelp(t *testing.T) {
+	var f flags
+	fs := newFlagSet(&f, io.Discard)
+	fs.VisitAll(func(fl *flag.Flag) {
+		documented := strings.Contains(fl.Usage, "(json format only)")
+		if documented != jsonOnlyFlags[fl.Name] {
+			t.Errorf("flag -%s: usage says json only = %v, jsonOnlyFlags = %v", fl.Name, documented, jsonOnlyFlags[fl.Name])
+		}
+	})
+}
+
+func TestPayloadFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-format", "json", "-chat-id", "42", "-thread-id", "3", "-reply-to", "9", "-disable-notification", "-protect-content"}
+	if code := run(args, strings.NewReader("**oi**"), &stdout, &stderr); code != 0 {
+		t.Fatalf("run = %d (stderr %q)", code, stderr.String())
+	}
+
+	var response types.MessageResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		t.Fatalf("output is not a MessageResponse: %v", err)
+	}
+	payload := response.Parts[0].Payload
+	if payload == nil {
+		t.Fatal("part has no payload")
+	}
+	if payload.ChatID != 42 || payload.MessageThreadID != 3 || !payload.DisableNotification || !payload.ProtectContent ||
+		payload.ReplyParameters == nil || payload.ReplyParameters.MessageID != 9 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
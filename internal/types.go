@@ -1,5 +1,11 @@
 package internal
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type BlockType int
 
 const (
@@ -9,8 +15,57 @@ const (
 	BlockTitle
 	BlockList
 	BlockQuote
+
+	// Tipos registrados pelo usuário começam a partir deste valor
+	BlockCustom BlockType = 100
 )
 
+func (t BlockType) String() string {
+	switch t {
+	case BlockText:
+		return "text"
+	case BlockCode:
+		return "code"
+	case BlockTable:
+		return "table"
+	case BlockTitle:
+		return "title"
+	case BlockList:
+		return "list"
+	case BlockQuote:
+		return "quote"
+	}
+	if t >= BlockCustom {
+		return "custom"
+	}
+	return "unknown"
+}
+
+// MarshalText usa o nome dos tipos embutidos e o número dos tipos
+// personalizados, que não têm nome
+func (t BlockType) MarshalText() ([]byte, error) {
+	if name := t.String(); name != "custom" && name != "unknown" {
+		return []byte(name), nil
+	}
+	return []byte(strconv.Itoa(int(t))), nil
+}
+
+func (t *BlockType) UnmarshalText(text []byte) error {
+	name := strings.ToLower(string(text))
+	for candidate := BlockText; candidate <= BlockQuote; candidate++ {
+		if candidate.String() == name {
+			*t = candidate
+			return nil
+		}
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil {
+		return fmt.Errorf("unknown block type %q", text)
+	}
+	*t = BlockType(n)
+	return nil
+}
+
 type Block struct {
 	Type    BlockType
 	Content string
@@ -24,6 +79,11 @@ const (
 
 const TelegramMaxLength = 4096
 
-var (
-	EnableLogs *bool
+const (
+	// SplitSafetyMargin é descontado do tamanho máximo ao dividir o texto,
+	// reservando espaço para os escapes adicionados na conversão
+	SplitSafetyMargin = 256
+	// MinMessageLength é o menor tamanho máximo de mensagem aceito; abaixo
+	// dele sobra pouco espaço útil depois da margem de segurança
+	MinMessageLength = 2 * SplitSafetyMargin
 )
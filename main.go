@@ -1,11 +1,13 @@
 package GoTeleMD
 
 import (
+	"time"
+	"unicode/utf8"
+
 	"github.com/sshturbo/GoTeleMD/internal"
 	"github.com/sshturbo/GoTeleMD/pkg/formatter"
 	"github.com/sshturbo/GoTeleMD/pkg/parser"
 	"github.com/sshturbo/GoTeleMD/pkg/types"
-	"github.com/sshturbo/GoTeleMD/pkg/utils"
 )
 
 const (
@@ -23,21 +25,100 @@ func NewConverter(options ...types.Option) *Converter {
 	for _, opt := range options {
 		opt(config)
 	}
-
-	utils.InitLogger(&config.EnableDebugLogs)
 	return &Converter{config: config}
 }
 
+// NewValidatedConverter cria um conversor como NewConverter, mas devolve um
+// erro se a configuração resultante não passar em Config.Validate
+func NewValidatedConverter(options ...types.Option) (*Converter, error) {
+	config := types.DefaultConfig()
+	for _, opt := range options {
+		opt(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Converter{config: config}, nil
+}
+
+// NewConverterFromConfig cria um conversor a partir de uma configuração já
+// montada, por exemplo com types.LoadConfig. Com nil, usa a configuração
+// padrão. Como NewValidatedConverter, devolve um erro se a configuração não
+// passar em Config.Validate.
+func NewConverterFromConfig(config *types.Config) (*Converter, error) {
+	if config == nil {
+		config = types.DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Converter{config: config}, nil
+}
+
 func (c *Converter) Convert(input string) (types.MessageResponse, error) {
+	metrics := c.config.MetricsOrNop()
+	startTime := time.Now()
+
+	response, stats, err := c.convert(input, metrics)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	} else {
+		stats.Stages.Total = time.Since(startTime)
+		metrics.Observe(types.MetricConvertDuration, stats.Stages.Total.Seconds())
+		metrics.Observe(types.MetricMessageParts, float64(response.TotalParts))
+		if c.config.IncludeStats {
+			response.Stats = &stats
+		}
+	}
+	metrics.Count(types.MetricConversions, 1, types.Label{Name: "result", Value: result})
+	return response, err
+}
+
+func (c *Converter) convert(input string, metrics types.Metrics) (types.MessageResponse, types.Stats, error) {
+	if input == "" {
+		return types.MessageResponse{}, types.Stats{}, types.NewError(types.ErrInvalidInput, "input cannot be empty", nil)
+	}
+
+	for _, middleware := range c.config.InputMiddleware {
+		input = middleware(input)
+	}
 	if input == "" {
-		return types.MessageResponse{}, types.NewError(types.ErrInvalidInput, "input cannot be empty", nil)
+		return types.MessageResponse{}, types.Stats{}, types.NewError(types.ErrInvalidInput, "input is empty after input middleware", nil)
 	}
 
-	resultado, err := formatter.ConvertMarkdown(input, c.config)
+	resultado, err := formatter.ConvertMarkdownDetailed(input, c.config)
 	if err != nil {
-		return types.MessageResponse{}, err
+		return types.MessageResponse{}, types.Stats{}, err
+	}
+	stats := resultado.Stats
+
+	splitStart := time.Now()
+	response, forcedSplits, err := parser.SplitText(resultado.Text, c.config.MaxMessageLength)
+	if err != nil {
+		return types.MessageResponse{}, types.Stats{}, err
+	}
+	stats.ForcedSplits += forcedSplits
+	stats.Stages.OutputSplit = time.Since(splitStart)
+	metrics.Observe(types.MetricSplitDuration, stats.Stages.OutputSplit.Seconds(),
+		types.Label{Name: "phase", Value: "output"})
+	response.Diagnostics = resultado.Diagnostics
+	if resultado.Keyboard != nil {
+		response.Parts[len(response.Parts)-1].ReplyMarkup = resultado.Keyboard
+	}
+
+	for i := range response.Parts {
+		response.Parts[i].ParseMode = c.config.OutputMode.ParseMode()
+		for _, middleware := range c.config.OutputMiddleware {
+			response.Parts[i] = middleware(response.Parts[i], response.TotalParts)
+		}
+		stats.PartSizes = append(stats.PartSizes, utf8.RuneCountInString(response.Parts[i].Content))
+	}
+	types.ApplyLinkPreview(response.Parts, c.config)
+	if c.config.Payload != nil {
+		types.BuildPayloads(response.Parts, c.config.Payload)
 	}
-	return parser.BreakLongText(resultado, c.config.MaxMessageLength)
+	return response, stats, nil
 }
 
 // Deprecated: Use NewConverter and Convert instead
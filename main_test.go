@@ -0,0 +1,40 @@
+package GoTeleMD
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestNewConverterFromConfig(t *testing.T) {
+	invalid := types.DefaultConfig()
+	invalid.SafetyLevel = 5
+
+	tests := []struct {
+		name    string
+		config  *types.Config
+		wantErr bool
+	}{
+		{"nil uses defaults", nil, false},
+		{"valid", types.DefaultConfig(), false},
+		{"invalid", invalid, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverterFromConfig(tt.config)
+			if tt.wantErr {
+				if !errors.Is(err, types.ErrInvalidConfig) || converter != nil {
+					t.Errorf("NewConverterFromConfig() = %v, %v; want nil, ErrInvalidConfig", converter, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConverterFromConfig() error: %v", err)
+			}
+			if _, err := converter.Convert("**oi**"); err != nil {
+				t.Errorf("Convert() error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package formatter
+
+import "github.com/sshturbo/GoTeleMD/pkg/types"
+
+// As funções abaixo mantêm as assinaturas antigas do pacote. Elas usam a
+// configuração padrão com o nível de segurança (ou as opções de tabela)
+// informado; para as demais opções use as variantes WithConfig.
+
+func ProcessText(input string, safetyLevel int) string {
+	return ProcessTextWithConfig(input, configWithSafetyLevel(safetyLevel))
+}
+
+func ProcessTitle(input string, safetyLevel int) string {
+	return ProcessTitleWithConfig(input, configWithSafetyLevel(safetyLevel))
+}
+
+func ProcessList(input string, safetyLevel int) string {
+	return ProcessListWithConfig(input, configWithSafetyLevel(safetyLevel))
+}
+
+func ProcessQuote(input string, safetyLevel int) string {
+	return ProcessQuoteWithConfig(input, configWithSafetyLevel(safetyLevel))
+}
+
+func ConvertTable(lines []string, align, ignoreSeparators bool) string {
+	config := types.DefaultConfig()
+	config.AlignTableColumns = align
+	config.IgnoreTableSeparator = ignoreSeparators
+	return ConvertTableWithConfig(lines, config)
+}
+
+func configWithSafetyLevel(safetyLevel int) *types.Config {
+	config := types.DefaultConfig()
+	config.SafetyLevel = safetyLevel
+	return config
+}
@@ -1,37 +1,40 @@
 package formatter
 
 import (
-	"encoding/json"
+	"log/slog"
 	"runtime"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sshturbo/GoTeleMD/internal"
 	"github.com/sshturbo/GoTeleMD/pkg/parser"
 	"github.com/sshturbo/GoTeleMD/pkg/types"
-	"github.com/sshturbo/GoTeleMD/pkg/utils"
 )
 
 type blockProcessor struct {
 	input    chan processTask
-	output   chan processResult
 	wg       sync.WaitGroup
-	errChan  chan error
 	config   *types.Config
 	stopChan chan struct{}
 }
 
 type processTask struct {
-	block  internal.Block
-	config *types.Config
-	index  int
-	part   int
-	total  int
+	block    internal.Block
+	config   *types.Config
+	reporter types.DiagnosticReporter
+	// results é o canal da parte a que o bloco pertence, para que resultados
+	// de partes diferentes nunca se misturem
+	results chan<- processResult
+	index   int
+	part    int
+	total   int
 }
 
 type processResult struct {
 	content string
+	escapes int
 	index   int
 	part    int
 }
@@ -44,8 +47,6 @@ func newBlockProcessor(config *types.Config) *blockProcessor {
 
 	p := &blockProcessor{
 		input:    make(chan processTask, config.WorkerQueueSize),
-		output:   make(chan processResult, config.WorkerQueueSize),
-		errChan:  make(chan error, numWorkers),
 		config:   config,
 		stopChan: make(chan struct{}),
 	}
@@ -69,23 +70,29 @@ func (p *blockProcessor) worker(id int) {
 			}
 
 			startTime := time.Now()
-
-			// Recuperação de pânico
-			defer func() {
-				if r := recover(); r != nil {
-					p.errChan <- types.NewError(types.ErrProcessingFailed, "worker panic", r.(error))
-				}
-			}()
-
-			rendered := RenderBlock(task.block, task.config)
-
-			if task.config.EnableDebugLogs {
-				utils.LogDebug("🔧 Worker %d - Bloco %d (Parte %d/%d): Tipo=%d, Tamanho=%d, Tempo=%v",
-					id, task.index+1, task.part, task.total, task.block.Type, len(rendered), time.Since(startTime))
+			rendered, failed := renderTask(task)
+			elapsed := time.Since(startTime)
+
+			blockType := types.Label{Name: "block_type", Value: task.block.Type.String()}
+			metrics := task.config.Metrics
+			metrics.Observe(types.MetricRenderDuration, elapsed.Seconds(), blockType)
+			metrics.Count(types.MetricBlocks, 1, blockType)
+			escapes := countEscapes(task.config, task.block.Type, rendered)
+			metrics.Count(types.MetricEscapedChars, int64(escapes), blockType)
+			if failed {
+				metrics.Count(types.MetricBlockFallbacks, 1, blockType)
 			}
 
+			task.config.Logger.Debug("block rendered",
+				slog.Int("worker", id),
+				slog.Int("part", task.part),
+				slog.Int("block", task.index),
+				slog.String("block_type", task.block.Type.String()),
+				slog.Int("size", len(rendered)),
+				slog.Duration("duration", elapsed))
+
 			select {
-			case p.output <- processResult{content: rendered, index: task.index, part: task.part}:
+			case task.results <- processResult{content: rendered, escapes: escapes, index: task.index, part: task.part}:
 			case <-p.stopChan:
 				return
 			}
@@ -96,55 +103,134 @@ func (p *blockProcessor) worker(id int) {
 	}
 }
 
+// renderTask renderiza um bloco isolando falhas: se o renderizador entrar em
+// pânico, o bloco é substituído pelo seu texto original escapado e a falha é
+// relatada como diagnóstico, sem interromper o restante da mensagem.
+func renderTask(task processTask) (rendered string, failed bool) {
+	// Cada bloco recebe uma cópia da configuração com o seu próprio
+	// Reporter, para que os diagnósticos saiam com a posição certa
+	blockConfig := *task.config
+	blockConfig.Reporter = task.reporter
+
+	defer func() {
+		if r := recover(); r != nil {
+			task.config.Logger.Error("block rendering failed",
+				slog.Int("part", task.part),
+				slog.Int("block", task.index),
+				slog.String("block_type", task.block.Type.String()),
+				slog.Any("panic", r))
+			report(&blockConfig, -1, types.SeverityError, types.DiagBlockRenderFailed,
+				"block rendering failed (%v); the block was rendered as plain text", r)
+			rendered, failed = renderFallback(task.block, &blockConfig), true
+		}
+	}()
+
+	return RenderBlock(task.block, &blockConfig), false
+}
+
 func (p *blockProcessor) close() {
 	close(p.stopChan)
 	close(p.input)
 	p.wg.Wait()
-	close(p.output)
-	close(p.errChan)
+}
+
+// Result é o resultado detalhado de uma conversão. Em Stats só são preenchidos
+// os dados conhecidos pelo formatter; os tamanhos das partes finais e o tempo
+// total ficam a cargo de quem divide o texto convertido.
+type Result struct {
+	Text        string
+	Diagnostics []types.Diagnostic
+	Stats       types.Stats
+	// Keyboard é o teclado extraído do fim do Markdown, quando
+	// Config.ExtractButtons está ligado
+	Keyboard *types.InlineKeyboardMarkup
 }
 
 func ConvertMarkdown(input string, config *types.Config) (string, error) {
+	result, err := ConvertMarkdownDetailed(input, config)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ConvertMarkdownDetailed converte o Markdown e devolve, junto com o texto,
+// os diagnósticos encontrados durante a conversão
+func ConvertMarkdownDetailed(input string, config *types.Config) (Result, error) {
 	if input == "" {
-		return "", types.NewError(types.ErrInvalidInput, "input cannot be empty", nil)
+		return Result{}, types.NewError(types.ErrInvalidInput, "input cannot be empty", nil)
 	}
 
-	startTime := time.Now()
-	defer func() {
-		utils.LogPerformance("Convert total", time.Since(startTime))
-	}()
+	// O logger e as métricas são resolvidos uma única vez, em uma cópia da
+	// configuração, para que todos os blocos da conversão usem os mesmos
+	resolved := *config
+	resolved.Logger = config.Log()
+	resolved.Metrics = config.MetricsOrNop()
+	config = &resolved
+	log := config.Logger
+	metrics := config.Metrics
 
-	utils.LogDebug("🔄 Iniciando conversão do Markdown")
-	utils.LogDebug("📊 Configurações:")
-	utils.LogDebug("   - Nível de segurança: %d", config.SafetyLevel)
-	utils.LogDebug("   - Tamanho máximo: %d", config.MaxMessageLength)
-	utils.LogDebug("   - Workers: %d", config.NumWorkers)
-	utils.LogDebug("   - Fila por worker: %d", config.WorkerQueueSize)
-	utils.LogDebug("   - Partes simultâneas: %d", config.MaxConcurrentParts)
+	startTime := time.Now()
+	log.Debug("conversion started",
+		slog.Int("input_size", len(input)),
+		slog.Int("safety_level", config.SafetyLevel),
+		slog.Int("max_message_length", config.MaxMessageLength),
+		slog.Int("workers", config.NumWorkers))
+
+	stats := types.Stats{
+		InputSize: utf8.RuneCountInString(input),
+		Blocks:    make(map[string]int),
+	}
 
-	utils.LogDebug("📝 Texto original:\n%s", input)
+	// As posições dos diagnósticos se referem sempre ao Markdown recebido; a
+	// extração dos botões só remove texto do fim, sem deslocar o resto
+	collector := newDiagnosticCollector(input)
+	var keyboard *types.InlineKeyboardMarkup
+	if config.ExtractButtons {
+		input, keyboard = ExtractButtons(input, config, collector.forBlock(0))
+		if strings.TrimSpace(input) == "" {
+			return Result{}, types.NewError(types.ErrInvalidInput, "input has no text besides buttons", nil)
+		}
+	}
 
-	response, err := parser.BreakLongText(strings.TrimSpace(input), config.MaxMessageLength)
+	splitStart := time.Now()
+	response, forcedSplits, err := parser.SplitText(strings.TrimSpace(input), config.MaxMessageLength)
 	if err != nil {
-		return "", types.NewError(types.ErrProcessingFailed, "failed to break text", err)
+		return Result{}, types.NewError(types.ErrProcessingFailed, "failed to break text", err)
 	}
-
-	if config.EnableDebugLogs {
-		jsonResponse, _ := json.MarshalIndent(response, "", "  ")
-		utils.LogDebug("📦 Divisão em partes:")
-		utils.LogDebug("   - Total de partes: %d", response.TotalParts)
-		utils.LogDebug("   - ID da mensagem: %s", response.MessageID)
-		utils.LogDebug("   - Estrutura JSON:\n%s", string(jsonResponse))
+	stats.ForcedSplits = forcedSplits
+	stats.Stages.InputSplit = time.Since(splitStart)
+	metrics.Observe(types.MetricSplitDuration, stats.Stages.InputSplit.Seconds(),
+		types.Label{Name: "phase", Value: "input"})
+
+	// Os blocos são separados antes do processamento paralelo para que a
+	// posição de cada um no Markdown original seja encontrada em ordem
+	partBlocks := make([][]internal.Block, len(response.Parts))
+	blockStarts := make([][]int, len(response.Parts))
+	cursor := 0
+	tokenizeStart := time.Now()
+	for i, part := range response.Parts {
+		partBlocks[i] = parser.Tokenize(strings.TrimSpace(part.Content), config.CustomBlocks...)
+		blockStarts[i], cursor = collector.locateBlocks(partBlocks[i], cursor)
+		for _, block := range partBlocks[i] {
+			stats.Blocks[block.Type.String()]++
+		}
 	}
+	stats.Stages.Tokenize = time.Since(tokenizeStart)
+	metrics.Observe(types.MetricTokenizeDuration, stats.Stages.Tokenize.Seconds())
 
+	log.Debug("input split into parts",
+		slog.String("message_id", response.MessageID),
+		slog.Int("parts", response.TotalParts))
+
+	renderStart := time.Now()
 	processor := newBlockProcessor(config)
 	defer processor.close()
 
 	outputParts := make([]string, len(response.Parts))
 
 	semaphore := make(chan struct{}, config.MaxConcurrentParts)
-	var errMutex sync.Mutex
-	var firstErr error
+	var statsMutex sync.Mutex
 
 	var wg sync.WaitGroup
 	for partIdx, part := range response.Parts {
@@ -155,47 +241,34 @@ func ConvertMarkdown(input string, config *types.Config) (string, error) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			blocks := parser.Tokenize(strings.TrimSpace(part.Content))
+			blocks := partBlocks[partIdx]
 			results := make(map[int]string)
-			pendingBlocks := len(blocks)
-
-			utils.LogDebug("🔍 Processando parte %d/%d", part.Part, response.TotalParts)
-			utils.LogDebug("   - Blocos encontrados: %d", len(blocks))
+			resultChan := make(chan processResult, len(blocks))
 
+			partStart := time.Now()
 			for i, block := range blocks {
-				select {
-				case processor.input <- processTask{
-					block:  block,
-					config: config,
-					index:  i,
-					part:   part.Part,
-					total:  response.TotalParts,
-				}:
-				case err := <-processor.errChan:
-					errMutex.Lock()
-					if firstErr == nil {
-						firstErr = err
-					}
-					errMutex.Unlock()
-					return
+				processor.input <- processTask{
+					block:    block,
+					config:   config,
+					reporter: collector.forBlock(blockStarts[partIdx][i]),
+					results:  resultChan,
+					index:    i,
+					part:     part.Part,
+					total:    response.TotalParts,
 				}
 			}
 
-			for pendingBlocks > 0 {
-				select {
-				case result := <-processor.output:
-					results[result.index] = result.content
-					pendingBlocks--
-				case err := <-processor.errChan:
-					errMutex.Lock()
-					if firstErr == nil {
-						firstErr = err
-					}
-					errMutex.Unlock()
-					return
-				}
+			escapes := 0
+			for range blocks {
+				result := <-resultChan
+				results[result.index] = result.content
+				escapes += result.escapes
 			}
 
+			statsMutex.Lock()
+			stats.EscapedChars += escapes
+			statsMutex.Unlock()
+
 			var output strings.Builder
 			output.Grow(len(part.Content))
 
@@ -221,24 +294,22 @@ func ConvertMarkdown(input string, config *types.Config) (string, error) {
 			formattedContent := strings.TrimSpace(output.String())
 			outputParts[partIdx] = formattedContent
 
-			if config.EnableDebugLogs {
-				utils.LogDebug("📤 Parte %d formatada:", part.Part)
-				utils.LogDebug("   - Tamanho: %d caracteres", len(formattedContent))
-				utils.LogDebug("   - Conteúdo:\n%s", formattedContent)
-			}
+			log.Debug("part rendered",
+				slog.Int("part", part.Part),
+				slog.Int("blocks", len(blocks)),
+				slog.Int("size", len(formattedContent)),
+				slog.Duration("duration", time.Since(partStart)))
 		}(partIdx, part)
 	}
 
 	wg.Wait()
-
-	if firstErr != nil {
-		return "", firstErr
-	}
+	stats.Stages.Render = time.Since(renderStart)
 
 	result := strings.TrimSpace(strings.Join(outputParts, "\n\n"))
-	utils.LogDebug("✅ Conversão finalizada")
-	utils.LogDebug("   - Tamanho final: %d caracteres", len(result))
-	utils.LogDebug("   - Partes geradas: %d", len(outputParts))
+	log.Debug("conversion finished",
+		slog.Int("parts", len(outputParts)),
+		slog.Int("size", len(result)),
+		slog.Duration("duration", time.Since(startTime)))
 
-	return result, nil
+	return Result{Text: result, Diagnostics: collector.result(), Stats: stats, Keyboard: keyboard}, nil
 }
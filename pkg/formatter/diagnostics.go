@@ -0,0 +1,101 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// report envia um diagnóstico ao Reporter da configuração, se houver
+func report(config *types.Config, offset int, severity types.Severity, code, format string, args ...interface{}) {
+	if config.Reporter == nil {
+		return
+	}
+	config.Reporter.Report(offset, severity, code, fmt.Sprintf(format, args...))
+}
+
+// diagnosticCollector acumula os diagnósticos de uma conversão, convertendo as
+// posições relativas a cada bloco em linha e coluna do Markdown original
+type diagnosticCollector struct {
+	mu          sync.Mutex
+	input       string
+	diagnostics []types.Diagnostic
+}
+
+func newDiagnosticCollector(input string) *diagnosticCollector {
+	return &diagnosticCollector{input: input}
+}
+
+// locateBlocks encontra a posição de cada bloco no Markdown original. Os blocos
+// chegam na ordem do texto, então a busca sempre continua de onde parou.
+// Blocos que não aparecem literalmente na entrada ficam com posição -1.
+func (c *diagnosticCollector) locateBlocks(blocks []internal.Block, cursor int) ([]int, int) {
+	starts := make([]int, len(blocks))
+	for i, block := range blocks {
+		idx := strings.Index(c.input[cursor:], block.Content)
+		if idx < 0 {
+			starts[i] = -1
+			continue
+		}
+		starts[i] = cursor + idx
+		cursor = starts[i] + len(block.Content)
+	}
+	return starts, cursor
+}
+
+// forBlock devolve um Reporter para o bloco que começa em start
+func (c *diagnosticCollector) forBlock(start int) types.DiagnosticReporter {
+	return &blockReporter{collector: c, start: start}
+}
+
+func (c *diagnosticCollector) add(d types.Diagnostic) {
+	c.mu.Lock()
+	c.diagnostics = append(c.diagnostics, d)
+	c.mu.Unlock()
+}
+
+// position converte um offset em bytes na entrada para linha e coluna
+func (c *diagnosticCollector) position(offset int) (int, int) {
+	if offset < 0 || offset > len(c.input) {
+		return 0, 0
+	}
+	before := c.input[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// result devolve os diagnósticos ordenados por posição
+func (c *diagnosticCollector) result() []types.Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i], c.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+type blockReporter struct {
+	collector *diagnosticCollector
+	start     int
+}
+
+func (r *blockReporter) Report(offset int, severity types.Severity, code, message string) {
+	d := types.Diagnostic{Severity: severity, Code: code, Message: message}
+	if r.start >= 0 {
+		if offset < 0 {
+			offset = 0
+		}
+		d.Line, d.Column = r.collector.position(r.start + offset)
+	}
+	r.collector.add(d)
+}
@@ -0,0 +1,68 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestConvertMarkdownDetailedPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		code   string
+		line   int
+		column int
+	}{
+		{"first line", "2 * 3", types.DiagUnbalancedMarker, 1, 3},
+		{"second line", "linha um\nsegunda * linha", types.DiagUnbalancedMarker, 2, 9},
+		{"after heading", "# Título\n\nOlá *mundo", types.DiagUnbalancedMarker, 3, 5},
+		{"column in runes", "ção * x", types.DiagUnbalancedMarker, 1, 5},
+		{"unclosed code block", "```go\nfmt.Println()", types.DiagUnclosedCodeBlock, 1, 1},
+		{"disallowed link", "texto [x](javascript:alert)", types.DiagLinkDisallowed, 1, 11},
+	}
+	config := types.DefaultConfig()
+	config.LinkPolicy = types.DefaultLinkPolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertMarkdownDetailed(tt.input, config)
+			if err != nil {
+				t.Fatalf("ConvertMarkdownDetailed(%q) error: %v", tt.input, err)
+			}
+			if len(result.Diagnostics) != 1 {
+				t.Fatalf("ConvertMarkdownDetailed(%q) diagnostics = %+v, want exactly one", tt.input, result.Diagnostics)
+			}
+			d := result.Diagnostics[0]
+			if d.Code != tt.code || d.Line != tt.line || d.Column != tt.column {
+				t.Errorf("diagnostic = %s %d:%d, want %s %d:%d", d.Code, d.Line, d.Column, tt.code, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestConvertMarkdownDetailedOrder(t *testing.T) {
+	input := "| a | b |\n|---|---|\n| 1 |\n| 1 | 2 | 3 |"
+	result, err := ConvertMarkdownDetailed(input, types.DefaultConfig())
+	if err != nil {
+		t.Fatalf("ConvertMarkdownDetailed error: %v", err)
+	}
+	if len(result.Diagnostics) != 2 {
+		t.Fatalf("diagnostics = %+v, want two", result.Diagnostics)
+	}
+	for i, want := range []int{1, 3} {
+		d := result.Diagnostics[i]
+		if d.Code != types.DiagTableRowPadded || d.Line != want {
+			t.Errorf("diagnostics[%d] = %s line %d, want %s line %d", i, d.Code, d.Line, types.DiagTableRowPadded, want)
+		}
+	}
+}
+
+func TestConvertMarkdownDetailedClean(t *testing.T) {
+	result, err := ConvertMarkdownDetailed("**tudo** certo", types.DefaultConfig())
+	if err != nil {
+		t.Fatalf("ConvertMarkdownDetailed error: %v", err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("diagnostics = %+v, want none", result.Diagnostics)
+	}
+}
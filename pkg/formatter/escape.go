@@ -0,0 +1,143 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// EscapeContext identifica em qual trecho de uma mensagem MarkdownV2 o texto
+// será inserido. Cada contexto tem suas próprias regras de escape.
+type EscapeContext int
+
+const (
+	// EscapeText é o contexto padrão: todos os 18 caracteres reservados
+	// (além da barra invertida) precisam de escape.
+	EscapeText EscapeContext = iota
+	// EscapeCode é usado dentro de entidades pre e code, onde apenas ` e \
+	// precisam de escape.
+	EscapeCode
+	// EscapeLinkURL é usado na parte (...) de um link, onde apenas ) e \
+	// precisam de escape.
+	EscapeLinkURL
+)
+
+const (
+	textReservedChars = "_*[]()~`>#+-=|{}.!\\"
+	codeReservedChars = "`\\"
+	linkReservedChars = ")\\"
+)
+
+func (ctx EscapeContext) reservedChars() string {
+	switch ctx {
+	case EscapeCode:
+		return codeReservedChars
+	case EscapeLinkURL:
+		return linkReservedChars
+	default:
+		return textReservedChars
+	}
+}
+
+// Escape aplica as regras de escape do MarkdownV2 correspondentes ao contexto.
+func Escape(text string, ctx EscapeContext) string {
+	return escapeWith(text, ctx, "")
+}
+
+// escapeWith escapa também os caracteres de extra, além dos reservados do
+// contexto
+func escapeWith(text string, ctx EscapeContext, extra string) string {
+	reserved := ctx.reservedChars() + extra
+
+	var result strings.Builder
+	result.Grow(len(text) + len(text)/4)
+	for _, r := range text {
+		if strings.ContainsRune(reserved, r) {
+			result.WriteByte('\\')
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// escapeFor aplica Escape apenas quando a saída é MarkdownV2; em texto simples
+// nada precisa de escape. Os caracteres de CustomEscapeChars também são
+// escapados no texto comum.
+func escapeFor(config *types.Config, text string, ctx EscapeContext) string {
+	if config.OutputMode == types.OutputPlainText {
+		return text
+	}
+	return escapeWith(text, ctx, customEscapeChars(config, ctx))
+}
+
+// customEscapeChars devolve os caracteres extras da configuração. Eles só
+// valem para texto comum: em código e URLs o escape de outros caracteres
+// mudaria o conteúdo.
+func customEscapeChars(config *types.Config, ctx EscapeContext) string {
+	if ctx != EscapeText {
+		return ""
+	}
+	return strings.Join(config.CustomEscapeChars, "")
+}
+
+// countEscapes conta os caracteres escapados de um texto já renderizado. Na
+// saída MarkdownV2 toda barra invertida inicia um escape, inclusive \\.
+func countEscapes(config *types.Config, blockType internal.BlockType, rendered string) int {
+	if config.OutputMode == types.OutputPlainText || config.SafetyLevelFor(blockType) == internal.SAFETYLEVELNONE {
+		return 0
+	}
+	count := 0
+	for i := 0; i < len(rendered)-1; i++ {
+		if rendered[i] == '\\' {
+			count++
+			i++
+		}
+	}
+	return count
+}
+
+// plainCodeBlock remove as linhas ``` de um bloco de código, mantendo apenas
+// o conteúdo.
+func plainCodeBlock(content string) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "```") {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isClosedCodeBlock verifica se o bloco de código termina com uma linha ```
+// além da linha de abertura
+func isClosedCodeBlock(content string) bool {
+	lines := strings.Split(content, "\n")
+	return len(lines) > 1 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```")
+}
+
+// renderCodeBlock escapa o conteúdo de um bloco de código cercado por ```,
+// preservando as linhas de abertura e fechamento.
+func renderCodeBlock(content string) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "```") {
+		return Escape(content, EscapeCode)
+	}
+
+	opening := lines[0]
+	body := lines[1:]
+	if isClosedCodeBlock(content) {
+		body = body[:len(body)-1]
+	}
+
+	var result strings.Builder
+	result.WriteString(opening)
+	result.WriteString("\n")
+	if len(body) > 0 {
+		result.WriteString(Escape(strings.Join(body, "\n"), EscapeCode))
+		result.WriteString("\n")
+	}
+	result.WriteString("```")
+	return result.String()
+}
@@ -0,0 +1,153 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		ctx  EscapeContext
+		want string
+	}{
+		{"text reserved", "_*[]()~`>#+-=|{}.!", EscapeText, `\_\*\[\]\(\)\~\` + "`" + `\>\#\+\-\=\|\{\}\.\!`},
+		{"text backslash", `a\b`, EscapeText, `a\\b`},
+		{"text plain", "Olá, mundo", EscapeText, "Olá, mundo"},
+		{"code", "a_b*c `d` \\e", EscapeCode, "a_b*c \\`d\\` \\\\e"},
+		{"link url", `https://ex.com/a_(b)\c`, EscapeLinkURL, `https://ex.com/a_(b\)\\c`},
+		{"empty", "", EscapeText, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Escape(tt.text, tt.ctx); got != tt.want {
+				t.Errorf("Escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessTextEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		level int
+		want  string
+	}{
+		{"bold and italic", "**negrito** e _itálico_", 1, "*negrito* e _itálico_"},
+		{"underscore bold", "__negrito__", 1, "*negrito*"},
+		{"strikethrough", "~~riscado~~", 1, "~riscado~"},
+		{"reserved punctuation", "Fim. Sim! 1-2 = 3 (ok)", 1, `Fim\. Sim\! 1\-2 \= 3 \(ok\)`},
+		{"unbalanced marker", "2 * 3", 1, `2 \* 3`},
+		{"unbalanced underscore", "snake_case", 1, `snake\_case`},
+		{"author escapes", `\*literal\* e 1\.`, 1, `\*literal\* e 1\.`},
+		{"author escaped backslash", `a\\b`, 1, `a\\b`},
+		{"inline code", "use `a_b*c` agora", 1, "use `a_b*c` agora"},
+		{"inline code backtick escape", "`a\\b`", 1, "`a\\\\b`"},
+		{"link", "[site.com](https://ex.com/a)", 1, `[site\.com](https://ex.com/a)`},
+		{"empty link text", "[](https://ex.com)", 1, `\[\]\(https://ex\.com\)`},
+		{"nested formatting", "**a _b_ c**", 1, "*a _b_ c*"},
+		{"empty markers", "****", 1, `\*\*\*\*`},
+		{"code fence", "antes ```x_y``` depois.", 1, "antes ```x_y``` depois\\."},
+		{"unclosed fence", "antes ``` depois", 1, "antes \\`\\`\\` depois"},
+		{"strict", "**a** [b](c)", 2, `\*\*a\*\* \[b\]\(c\)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProcessText(tt.input, tt.level); got != tt.want {
+				t.Errorf("ProcessText(%q, %d) = %q, want %q", tt.input, tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountEscapes(t *testing.T) {
+	plain := types.DefaultConfig()
+	plain.OutputMode = types.OutputPlainText
+	none := types.DefaultConfig()
+	none.SafetyLevel = 0
+	codeNone := types.DefaultConfig()
+	codeNone.BlockSafetyLevels = map[types.BlockType]int{types.BlockCode: 0}
+
+	tests := []struct {
+		name     string
+		config   *types.Config
+		block    internal.BlockType
+		rendered string
+		want     int
+	}{
+		{"none escaped", types.DefaultConfig(), internal.BlockText, "*negrito*", 0},
+		{"single escapes", types.DefaultConfig(), internal.BlockText, `Fim\. Sim\!`, 2},
+		{"escaped backslash counts once", types.DefaultConfig(), internal.BlockText, `a\\b`, 1},
+		{"escaped backslash before escape", types.DefaultConfig(), internal.BlockText, `\\\.`, 2},
+		{"trailing backslash", types.DefaultConfig(), internal.BlockText, `a\`, 0},
+		{"plain text output", plain, internal.BlockText, `a\.b`, 0},
+		{"safety level none", none, internal.BlockText, `a\.b`, 0},
+		{"block level none", codeNone, internal.BlockCode, `a\.b`, 0},
+		{"other block keeps base level", codeNone, internal.BlockText, `a\.b`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countEscapes(tt.config, tt.block, tt.rendered); got != tt.want {
+				t.Errorf("countEscapes(%q) = %d, want %d", tt.rendered, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeWith(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		ctx   EscapeContext
+		extra string
+		want  string
+	}{
+		{"no extra", "a@b.", EscapeText, "", `a@b\.`},
+		{"extra chars", "a@b$c.", EscapeText, "@$", `a\@b\$c\.`},
+		{"extra already reserved", "a.b", EscapeText, ".", `a\.b`},
+		{"extra in code context", "a@b", EscapeCode, "@", `a\@b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeWith(tt.text, tt.ctx, tt.extra); got != tt.want {
+				t.Errorf("escapeWith(%q, %q) = %q, want %q", tt.text, tt.extra, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomEscapeChars(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		level int
+		want  string
+	}{
+		{"text", "mail@ex", 1, `mail\@ex`},
+		{"author escape", `mail\@ex`, 1, `mail\@ex`},
+		{"inline code untouched", "`a@b`", 1, "`a@b`"},
+		{"link url untouched", "[x](https://ex.com/@a)", 1, "[x](https://ex.com/@a)"},
+		{"strict", "a@b", 2, `a\@b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.SafetyLevel = tt.level
+			config.CustomEscapeChars = []string{"@"}
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	plain := types.DefaultConfig()
+	plain.OutputMode = types.OutputPlainText
+	plain.CustomEscapeChars = []string{"@"}
+	if got := ProcessTextWithConfig("a@b", plain); got != "a@b" {
+		t.Errorf("plain output = %q, want %q", got, "a@b")
+	}
+}
@@ -0,0 +1,84 @@
+package formatter
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+var ticketExtension = types.InlineExtension{
+	Name:    "ticket",
+	Pattern: regexp.MustCompile(`\{\{ticket:([A-Z]+-\d+)\}\}`),
+	Render: func(m []string, config *types.Config) string {
+		return RenderInline("["+m[1]+"](https://jira.example.com/browse/"+m[1]+")", config)
+	},
+}
+
+func TestInlineExtensions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"rendered", "veja {{ticket:ABC-123}}.", `veja [ABC\-123](https://jira.example.com/browse/ABC-123)\.`},
+		{"next to formatting", "**urgente**: {{ticket:X-1}}", `*urgente*: [X\-1](https://jira.example.com/browse/X-1)`},
+		{"inside inline code", "`{{ticket:ABC-1}}`", "`{{ticket:ABC-1}}`"},
+		{"not matching", "{{ticket:abc}}", `\{\{ticket:abc\}\}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			types.WithInlineExtension(ticketExtension)(config)
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineExtensionOutputIsVerbatim(t *testing.T) {
+	config := types.DefaultConfig()
+	types.WithInlineExtension(types.InlineExtension{
+		Pattern: regexp.MustCompile(`:ok:`),
+		Render:  func([]string, *types.Config) string { return "*✔*" },
+	})(config)
+
+	if got, want := ProcessTextWithConfig("feito :ok: (1)", config), `feito *✔* \(1\)`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithInlineExtensionIgnoresIncomplete(t *testing.T) {
+	config := types.DefaultConfig()
+	types.WithInlineExtension(types.InlineExtension{Name: "sem padrão"})(config)
+	if len(config.InlineExtensions) != 0 {
+		t.Errorf("extension without pattern and render was registered")
+	}
+}
+
+func TestInlineExtensionsAtSafetyLevelNone(t *testing.T) {
+	config := types.DefaultConfig()
+	config.SafetyLevel = 0
+	types.WithInlineExtension(types.InlineExtension{
+		Pattern: regexp.MustCompile(`:ok:`),
+		Render:  func([]string, *types.Config) string { return "**feito** [x](y)" },
+	})(config)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"output is not reformatted", ":ok:", "**feito** [x](y)"},
+		{"surrounding text is formatted", "**a** :ok: __b__", "*a* **feito** [x](y) *b*"},
+		{"several matches", ":ok::ok:", "**feito** [x](y)**feito** [x](y)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
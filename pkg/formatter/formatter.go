@@ -2,260 +2,66 @@ package formatter
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
-	"unicode"
 
 	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
 	"github.com/sshturbo/GoTeleMD/pkg/utils"
 )
 
-// isBalancedFormatting verifica se os caracteres de formatação estão balanceados
-func isBalancedFormatting(text string, char string) bool {
-	count := 0
-	escaped := false
-	for _, r := range text {
-		c := string(r)
-		if c == "\\" {
-			escaped = !escaped
-			continue
-		}
-		if !escaped && c == char {
-			count++
-		}
-		escaped = false
-	}
-	return count%2 == 0
-}
-
-// findUnbalancedFormatting encontra posições de caracteres de formatação não balanceados
-func findUnbalancedFormatting(text string) map[string][]int {
-	unbalanced := make(map[string][]int)
-	formatChars := []string{"*", "_", "~", "`"}
-
-	for _, char := range formatChars {
-		var stack []int
-		escaped := false
-
-		for i, r := range text {
-			c := string(r)
-			if c == "\\" {
-				escaped = !escaped
-				continue
-			}
-			if !escaped && c == char {
-				if len(stack) > 0 {
-					stack = stack[:len(stack)-1] // Remove posição pareada
-				} else {
-					stack = append(stack, i) // Adiciona posição não pareada
-				}
-			}
-			escaped = false
-		}
-
-		if len(stack) > 0 {
-			unbalanced[char] = stack
-		}
-	}
-
-	return unbalanced
-}
-
-func escapeSpecialChars(text string) string {
-	escaped := text
-	specialChars := []string{"[", "]", "(", ")", ">", "#", "+", "-", "=", "|", "{", "}", ".", "!", "\\."}
-
-	// Primeiro escapa as barras invertidas
-	escaped = strings.ReplaceAll(escaped, "\\", "\\\\")
-
-	// Depois escapa os caracteres especiais não relacionados à formatação
-	for _, char := range specialChars {
-		if char == "\\." {
-			// Trata especialmente o ponto para não escapar dentro de números
-			escaped = regexp.MustCompile(`(\d*?)\.(\d+)`).ReplaceAllString(escaped, "${1}\\.${2}")
-		} else {
-			escaped = strings.ReplaceAll(escaped, char, "\\"+char)
-		}
-	}
-
-	// Por fim, trata os caracteres de formatação
-	formatChars := []string{"*", "_", "~", "`"}
-	for _, char := range formatChars {
-		if !isBalancedFormatting(text, char) {
-			// Se não estiver balanceado, escapa todas as ocorrências
-			escaped = strings.ReplaceAll(escaped, char, "\\"+char)
-		}
-	}
-
-	return escaped
-}
-
-func escapeNonFormatChars(text string) string {
-	var result strings.Builder
-	result.Grow(len(text) * 2)
-
-	// Encontra caracteres de formatação não balanceados
-	unbalanced := findUnbalancedFormatting(text)
-
-	specialChars := []string{"#", "+", "-", "=", "|", ".", "!", "(", ")", "{", "}"}
-	escaped := false
-	lastWasDigit := false
-
-	for i, r := range text {
-		c := string(r)
-
-		if c == "\\" {
-			escaped = !escaped
-			result.WriteString(c)
-			lastWasDigit = false
-			continue
-		}
-
-		if escaped {
-			result.WriteString(c)
-			escaped = false
-			lastWasDigit = unicode.IsDigit(r)
-			continue
-		}
-
-		// Verifica se é um caractere de formatação
-		if c == "*" || c == "_" || c == "~" || c == "`" {
-			// Se a posição atual está na lista de não balanceados, escapa
-			if positions, exists := unbalanced[c]; exists {
-				isUnbalanced := false
-				for _, pos := range positions {
-					if pos == i {
-						isUnbalanced = true
-						break
-					}
-				}
-				if isUnbalanced {
-					result.WriteString("\\")
-				}
-			}
-			result.WriteString(c)
-			lastWasDigit = false
-			continue
-		}
-
-		// Escapa caracteres especiais não relacionados à formatação
-		for _, special := range specialChars {
-			if c == special {
-				// Não escapa o ponto se estiver entre dígitos
-				if c == "." && lastWasDigit {
-					nextIsDigit := false
-					if i+1 < len(text) {
-						nextRune := []rune(text)[i+1]
-						nextIsDigit = unicode.IsDigit(nextRune)
-					}
-					if nextIsDigit {
-						break
-					}
-				}
-				result.WriteString("\\")
-				break
-			}
-		}
-
-		result.WriteString(c)
-		escaped = false
-		lastWasDigit = unicode.IsDigit(r)
-	}
-
-	return result.String()
-}
-
-func ProcessText(input string, safetyLevel int) string {
+// ProcessTextWithConfig converte um bloco de texto com a configuração informada
+func ProcessTextWithConfig(input string, config *types.Config) string {
+	safetyLevel := safetyLevelFor(config, internal.BlockText)
 	if safetyLevel == internal.SAFETYLEVELSTRICT {
-		return escapeSpecialChars(input)
+		return escapeFor(config, input, EscapeText)
 	}
 
 	if safetyLevel == internal.SAFETYLEVELBASIC {
 		parts := strings.Split(input, "```")
-		for i := range parts {
+		// Um ``` sem fechamento é tratado como texto comum
+		if len(parts)%2 == 0 {
+			last := len(parts) - 1
+			report(config, len(input)-len(parts[last])-3, types.SeverityWarning, types.DiagUnclosedCodeBlock,
+				"``` without a closing fence was escaped and will be shown literally")
+			parts[last-1] += "```" + parts[last]
+			parts = parts[:last]
+		}
+		offset := 0
+		for i, part := range parts {
 			if i%2 == 0 {
-				text := parts[i]
-
-				// Primeiro faz o processamento de formatação inline
-				text = ProcessInlineFormatting(text)
-				text = processLinks(text)
-
-				// Depois trata os pontos e outros caracteres especiais
-				text = processSpecialChars(text)
-
-				// Por fim, processa blocos de código inline
-				var result strings.Builder
-				lastIndex := 0
-				for _, match := range utils.InlineCodePattern.FindAllStringSubmatchIndex(text, -1) {
-					prefix := text[lastIndex:match[0]]
-					result.WriteString(escapeNonFormatChars(prefix))
-					codeContent := text[match[2]:match[3]]
-					result.WriteString("`")
-					result.WriteString(escapeSpecialChars(codeContent))
-					result.WriteString("`")
-					lastIndex = match[1]
-				}
-				if lastIndex < len(text) {
-					result.WriteString(escapeNonFormatChars(text[lastIndex:]))
-				}
-				parts[i] = result.String()
+				parts[i] = renderInlineAt(part, offset, config)
 			} else {
-				parts[i] = escapeSpecialChars(parts[i])
+				parts[i] = escapeFor(config, part, EscapeCode)
 			}
+			offset += len(part) + 3
 		}
 		return strings.Join(parts, "```")
 	}
 
-	text := input
-	text = ProcessInlineFormatting(text)
-	text = processLinks(text)
-	return text
+	return formatUnescaped(input, config)
 }
 
-func processSpecialChars(text string) string {
-	var result strings.Builder
-	runes := []rune(text)
-	lastWasDigit := false
-
-	for i := 0; i < len(runes); i++ {
-		r := runes[i]
-		c := string(r)
-
-		if unicode.IsDigit(r) {
-			lastWasDigit = true
-			result.WriteRune(r)
-			continue
-		}
-
-		// Escapa bullet point no início da linha
-		if c == "•" {
-			prevIsNewline := i == 0 || (i > 0 && string(runes[i-1]) == "\n")
-			if prevIsNewline {
-				result.WriteString("\\•")
-				continue
-			}
-		}
-
-		if c == "." {
-			// Verifica se é um ponto entre números (número decimal)
-			if lastWasDigit && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
-				result.WriteRune(r)
-				continue
-			}
-
-			// Escapa o ponto em todas as outras situações
-			result.WriteString("\\.")
-		} else {
-			result.WriteRune(r)
-		}
-
-		lastWasDigit = false
+// safetyLevelFor devolve o nível de segurança efetivo de um tipo de bloco.
+// Texto simples não tem marcação a escapar, então sempre usa o caminho BASIC,
+// que remove a formatação Markdown sem deixar marcadores soltos.
+func safetyLevelFor(config *types.Config, blockType internal.BlockType) int {
+	if config.OutputMode == types.OutputPlainText {
+		return internal.SAFETYLEVELBASIC
 	}
+	return config.SafetyLevelFor(blockType)
+}
 
-	return result.String()
+// formatInline aplica a formatação inline conforme o nível de segurança.
+// No nível NONE o texto é convertido sem nenhum escape. offset é a posição do
+// texto dentro do conteúdo do bloco, usada nos diagnósticos.
+func formatInline(text string, offset, safetyLevel int, config *types.Config) string {
+	if safetyLevel == internal.SAFETYLEVELNONE {
+		return formatUnescaped(text, config)
+	}
+	return renderInlineAt(text, offset, config)
 }
 
-func processLinks(text string) string {
+func processLinks(text string, policy *types.LinkPolicy) string {
 	return utils.LinkPattern.ReplaceAllStringFunc(text, func(m string) string {
 		match := utils.LinkPattern.FindStringSubmatch(m)
 		linkText := match[1]
@@ -277,10 +83,30 @@ func processLinks(text string) string {
 			}
 			return i
 		})
-		return fmt.Sprintf("[%s](%s)", linkText, match[2])
+		if policy == nil || isTelegramEntityURL(match[2]) {
+			return fmt.Sprintf("[%s](%s)", linkText, match[2])
+		}
+		url, ok := policy.Resolve(match[2])
+		if ok {
+			return fmt.Sprintf("[%s](%s)", linkText, url)
+		}
+		switch policy.OnDisallowed {
+		case types.LinkDrop:
+			return ""
+		case types.LinkShowURLAsCode:
+			return fmt.Sprintf("%s (`%s`)", linkText, match[2])
+		default:
+			return linkText
+		}
 	})
 }
 
+// isTelegramEntityURL identifica URLs de menções e emojis personalizados, que
+// não são links de verdade e por isso ficam fora da política de URLs.
+func isTelegramEntityURL(url string) bool {
+	return strings.HasPrefix(url, "tg://user?") || strings.HasPrefix(url, "tg://emoji?")
+}
+
 func ProcessInlineFormatting(text string) string {
 	text = utils.BoldPattern.ReplaceAllStringFunc(text, func(m string) string {
 		match := utils.BoldPattern.FindStringSubmatch(m)
@@ -315,25 +141,36 @@ func ProcessInlineFormatting(text string) string {
 	return text
 }
 
-func ProcessTitle(input string, safetyLevel int) string {
+// ProcessTitleWithConfig converte um título com a configuração informada
+func ProcessTitleWithConfig(input string, config *types.Config) string {
+	safetyLevel := safetyLevelFor(config, internal.BlockTitle)
 	if safetyLevel >= internal.SAFETYLEVELSTRICT {
-		return escapeSpecialChars(input)
+		return escapeFor(config, input, EscapeText)
 	}
 
-	return utils.TitlePattern.ReplaceAllStringFunc(input, func(m string) string {
-		match := utils.TitlePattern.FindStringSubmatch(m)
-		level := len(match[1])
-		title := strings.TrimSpace(match[2])
-		if level <= 2 {
-			return fmt.Sprintf("*%s*", title)
-		}
-		return fmt.Sprintf("_%s_", title)
-	})
+	loc := utils.TitlePattern.FindStringSubmatchIndex(input)
+	if loc == nil {
+		return formatInline(input, 0, safetyLevel, config)
+	}
+
+	level := loc[3] - loc[2]
+	raw := input[loc[4]:loc[5]]
+	offset := loc[4] + len(raw) - len(strings.TrimLeft(raw, " \t"))
+	title := formatInline(strings.TrimSpace(raw), offset, safetyLevel, config)
+	if config.OutputMode == types.OutputPlainText {
+		return title
+	}
+	if level <= 2 {
+		return fmt.Sprintf("*%s*", title)
+	}
+	return fmt.Sprintf("_%s_", title)
 }
 
-func ProcessList(input string, safetyLevel int) string {
+// ProcessListWithConfig converte uma lista com a configuração informada
+func ProcessListWithConfig(input string, config *types.Config) string {
+	safetyLevel := safetyLevelFor(config, internal.BlockList)
 	if safetyLevel >= internal.SAFETYLEVELSTRICT {
-		return escapeSpecialChars(input)
+		return escapeFor(config, input, EscapeText)
 	}
 
 	var builder strings.Builder
@@ -342,7 +179,11 @@ func ProcessList(input string, safetyLevel int) string {
 	isFirstItem := true
 	lastLineWasList := false
 
+	lineStart := 0
 	for _, line := range lines {
+		offset := lineStart
+		lineStart += len(line) + 1
+
 		trimmedLine := strings.TrimSpace(line)
 		if trimmedLine == "" {
 			if lastLineWasList {
@@ -360,10 +201,8 @@ func ProcessList(input string, safetyLevel int) string {
 			if !isFirstItem && lastLineWasList {
 				builder.WriteString("\n")
 			}
-			match := utils.ListItemPattern.FindStringSubmatch(line)
-			item := match[1]
-			item = ProcessInlineFormatting(item)
-			item = escapeNonFormatChars(item)
+			loc := utils.ListItemPattern.FindStringSubmatchIndex(line)
+			item := formatInline(line[loc[2]:loc[3]], offset+loc[2], safetyLevel, config)
 			builder.WriteString(fmt.Sprintf("• %s", item))
 			isFirstItem = false
 			lastLineWasList = true
@@ -371,11 +210,9 @@ func ProcessList(input string, safetyLevel int) string {
 			if !isFirstItem && lastLineWasList {
 				builder.WriteString("\n")
 			}
-			match := utils.OrderedListPattern.FindStringSubmatch(line)
-			item := match[1]
-			item = ProcessInlineFormatting(item)
-			item = escapeNonFormatChars(item)
-			builder.WriteString(fmt.Sprintf("%d\\. %s", listCounter, item))
+			loc := utils.OrderedListPattern.FindStringSubmatchIndex(line)
+			item := formatInline(line[loc[2]:loc[3]], offset+loc[2], safetyLevel, config)
+			builder.WriteString(fmt.Sprintf("%d%s %s", listCounter, escapeFor(config, ".", EscapeText), item))
 			listCounter++
 			isFirstItem = false
 			lastLineWasList = true
@@ -385,9 +222,7 @@ func ProcessList(input string, safetyLevel int) string {
 			} else if !isFirstItem {
 				builder.WriteString("\n")
 			}
-			if safetyLevel == internal.SAFETYLEVELBASIC {
-				line = escapeNonFormatChars(line)
-			}
+			line = formatInline(line, offset, safetyLevel, config)
 			builder.WriteString(line)
 			listCounter = 1
 			isFirstItem = false
@@ -402,18 +237,23 @@ func ProcessList(input string, safetyLevel int) string {
 	return result
 }
 
-func ProcessQuote(input string, safetyLevel int) string {
+// ProcessQuoteWithConfig converte uma citação com a configuração informada
+func ProcessQuoteWithConfig(input string, config *types.Config) string {
+	safetyLevel := safetyLevelFor(config, internal.BlockQuote)
 	if safetyLevel >= internal.SAFETYLEVELSTRICT {
-		return escapeSpecialChars(input)
+		return escapeFor(config, input, EscapeText)
 	}
 
 	lines := strings.Split(input, "\n")
 	var result []string
 
+	lineStart := 0
 	for _, line := range lines {
-		if utils.BlockquotePattern.MatchString(line) {
-			match := utils.BlockquotePattern.FindStringSubmatch(line)
-			quote := ProcessInlineFormatting(match[1])
+		offset := lineStart
+		lineStart += len(line) + 1
+
+		if loc := utils.BlockquotePattern.FindStringSubmatchIndex(line); loc != nil {
+			quote := formatInline(line[loc[2]:loc[3]], offset+loc[2], safetyLevel, config)
 			result = append(result, fmt.Sprintf("> %s", quote))
 		} else {
 			result = append(result, line)
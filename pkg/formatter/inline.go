@@ -0,0 +1,371 @@
+package formatter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+	"github.com/sshturbo/GoTeleMD/pkg/utils"
+)
+
+// inlineMatch guarda os submatches de uma ocorrência e a posição de cada um
+// dentro do conteúdo do bloco (-1 quando o grupo não participou)
+type inlineMatch struct {
+	groups  []string
+	offsets []int
+}
+
+// inlineRule associa um padrão inline à função que renderiza cada ocorrência.
+// A função devolve o trecho já pronto para a saída.
+type inlineRule struct {
+	pattern *regexp.Regexp
+	render  func(m inlineMatch) string
+}
+
+type inlineRenderer struct {
+	config *types.Config
+	plain  bool
+	// report indica se os diagnósticos devem ser relatados; só faz sentido
+	// quando o texto renderizado vem do conteúdo do bloco
+	report bool
+	rules  []inlineRule
+}
+
+func newInlineRenderer(config *types.Config) *inlineRenderer {
+	r := &inlineRenderer{
+		config: config,
+		plain:  config.OutputMode == types.OutputPlainText,
+	}
+	// Extensões vêm logo depois do código inline, para que nada dentro de
+	// `...` seja expandido. Menções e emojis vêm antes dos links para que
+	// nunca passem pela política de URLs
+	r.rules = []inlineRule{
+		{pattern: utils.InlineCodePattern, render: r.renderInlineCode},
+	}
+	for _, ext := range config.InlineExtensions {
+		r.rules = append(r.rules, inlineRule{pattern: ext.Pattern, render: r.extensionRenderer(ext)})
+	}
+	r.rules = append(r.rules, []inlineRule{
+		{pattern: utils.CustomEmojiPattern, render: r.renderCustomEmoji},
+		{pattern: utils.MentionPattern, render: r.renderMention},
+		{pattern: utils.LinkPattern, render: r.renderLink},
+		{pattern: utils.BoldPattern, render: r.renderBold},
+		{pattern: utils.RiscadoPattern, render: r.renderStrikethrough},
+		{pattern: utils.ItalicPattern, render: r.renderItalic},
+	}...)
+	return r
+}
+
+// render percorre o texto procurando a ocorrência mais próxima entre todas as
+// regras. O que nenhuma regra reconhece é escapado como texto comum, de modo
+// que caracteres de formatação soltos nunca chegam ao Telegram sem escape.
+// base é a posição do texto dentro do conteúdo do bloco.
+func (r *inlineRenderer) render(text string, base int) string {
+	var result strings.Builder
+	result.Grow(len(text) + len(text)/4)
+
+	// As regras procuram marcadores na versão mascarada, mas os trechos são
+	// sempre recortados do texto original
+	masked := maskEscapes(text)
+
+	pos := 0
+	for pos < len(text) {
+		rule, loc := r.nextMatch(masked[pos:])
+		if rule == nil {
+			break
+		}
+
+		start, end := pos+loc[0], pos+loc[1]
+		result.WriteString(r.escapeText(text[pos:start], base+pos))
+		result.WriteString(rule.render(newInlineMatch(text, loc, pos, base)))
+		pos = end
+	}
+
+	if pos < len(text) {
+		result.WriteString(r.escapeText(text[pos:], base+pos))
+	}
+	return result.String()
+}
+
+// maskEscapes substitui cada par \X por bytes neutros de mesmo tamanho, para
+// que caracteres escapados pelo autor nunca sejam tratados como marcadores.
+func maskEscapes(text string) string {
+	return utils.EscapedCharPattern.ReplaceAllString(text, "\x00\x00")
+}
+
+// escapeText escapa texto comum respeitando os escapes já feitos pelo autor:
+// \X vira o caractere X literal, escapado novamente apenas se for reservado
+// ou estiver em extra.
+func escapeText(text, extra string) string {
+	var result strings.Builder
+	last := 0
+	for _, loc := range utils.EscapedCharPattern.FindAllStringSubmatchIndex(text, -1) {
+		result.WriteString(escapeWith(text[last:loc[0]], EscapeText, extra))
+		result.WriteString(escapeWith(text[loc[2]:loc[3]], EscapeText, extra))
+		last = loc[1]
+	}
+	result.WriteString(escapeWith(text[last:], EscapeText, extra))
+	return result.String()
+}
+
+func (r *inlineRenderer) escapeText(text string, base int) string {
+	if r.plain {
+		return utils.EscapedCharPattern.ReplaceAllString(text, "$1")
+	}
+	if r.report {
+		r.reportUnbalancedMarkers(text, base)
+	}
+	return escapeText(text, customEscapeChars(r.config, EscapeText))
+}
+
+// reportUnbalancedMarkers relata os marcadores de formatação que sobraram no
+// texto comum e que por isso serão escapados
+func (r *inlineRenderer) reportUnbalancedMarkers(text string, base int) {
+	masked := maskEscapes(text)
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '*', '_', '~', '`':
+			r.reportf(base+i, types.DiagUnbalancedMarker,
+				"unbalanced %q was escaped and will be shown literally", masked[i])
+		}
+	}
+}
+
+func (r *inlineRenderer) escape(text string, ctx EscapeContext) string {
+	return escapeFor(r.config, text, ctx)
+}
+
+// nextMatch devolve a regra com a ocorrência mais à esquerda. Em caso de
+// empate vence a regra declarada primeiro.
+func (r *inlineRenderer) nextMatch(text string) (*inlineRule, []int) {
+	var best *inlineRule
+	var bestLoc []int
+	for i := range r.rules {
+		loc := r.rules[i].pattern.FindStringSubmatchIndex(text)
+		if loc == nil || loc[1] == loc[0] {
+			continue
+		}
+		if best == nil || loc[0] < bestLoc[0] {
+			best = &r.rules[i]
+			bestLoc = loc
+		}
+	}
+	return best, bestLoc
+}
+
+func newInlineMatch(text string, loc []int, pos, base int) inlineMatch {
+	m := inlineMatch{
+		groups:  make([]string, len(loc)/2),
+		offsets: make([]int, len(loc)/2),
+	}
+	for i := range m.groups {
+		m.offsets[i] = -1
+		if loc[2*i] >= 0 {
+			m.groups[i] = text[pos+loc[2*i] : pos+loc[2*i+1]]
+			m.offsets[i] = base + pos + loc[2*i]
+		}
+	}
+	return m
+}
+
+// trimmed devolve o grupo sem espaços nas pontas e a posição já ajustada
+func (m inlineMatch) trimmed(i int) (string, int) {
+	group := m.groups[i]
+	content := strings.TrimLeft(group, " \t\r\n")
+	offset := m.offsets[i] + len(group) - len(content)
+	return strings.TrimSpace(content), offset
+}
+
+func (r *inlineRenderer) wrap(marker string, m inlineMatch, group int) string {
+	content, offset := m.trimmed(group)
+	if content == "" {
+		return r.escapeText(m.groups[0], m.offsets[0])
+	}
+	if r.plain {
+		return r.render(content, offset)
+	}
+	return marker + r.render(content, offset) + marker
+}
+
+func (r *inlineRenderer) extensionRenderer(ext types.InlineExtension) func(m inlineMatch) string {
+	return func(m inlineMatch) string {
+		return ext.Render(m.groups, r.config)
+	}
+}
+
+// formatUnescaped converte o texto no caminho sem escape (nível NONE), onde não
+// há renderizador por trechos. A saída das extensões é colada como um trecho
+// opaco: só o texto entre as ocorrências passa pelos padrões de formatação.
+func formatUnescaped(text string, config *types.Config) string {
+	convert := func(s string) string {
+		return processLinks(ProcessInlineFormatting(s), config.LinkPolicy)
+	}
+
+	var result strings.Builder
+	pos := 0
+	for pos < len(text) {
+		ext, loc := nextExtensionMatch(text[pos:], config.InlineExtensions)
+		if ext == nil {
+			break
+		}
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = text[pos+loc[2*i] : pos+loc[2*i+1]]
+			}
+		}
+		result.WriteString(convert(text[pos : pos+loc[0]]))
+		result.WriteString(ext.Render(groups, config))
+		pos += loc[1]
+	}
+	result.WriteString(convert(text[pos:]))
+	return result.String()
+}
+
+// nextExtensionMatch devolve a extensão com a ocorrência mais à esquerda. Em
+// caso de empate vence a extensão registrada primeiro.
+func nextExtensionMatch(text string, exts []types.InlineExtension) (*types.InlineExtension, []int) {
+	var best *types.InlineExtension
+	var bestLoc []int
+	for i := range exts {
+		loc := exts[i].Pattern.FindStringSubmatchIndex(text)
+		if loc == nil || loc[1] == loc[0] {
+			continue
+		}
+		if best == nil || loc[0] < bestLoc[0] {
+			best = &exts[i]
+			bestLoc = loc
+		}
+	}
+	return best, bestLoc
+}
+
+func (r *inlineRenderer) renderInlineCode(m inlineMatch) string {
+	if r.plain {
+		return m.groups[1]
+	}
+	return "`" + Escape(m.groups[1], EscapeCode) + "`"
+}
+
+// renderMention renderiza [nome](tg://user?id=123). Um ID inválido faria o
+// Telegram recusar a mensagem, então nesse caso só o nome é mantido.
+func (r *inlineRenderer) renderMention(m inlineMatch) string {
+	name, offset := m.trimmed(1)
+	if name == "" {
+		return r.escapeText(m.groups[0], m.offsets[0])
+	}
+	if !isTelegramID(m.groups[2]) {
+		r.reportf(m.offsets[2], types.DiagInvalidMentionID,
+			"invalid user ID %q in mention; only the name was kept", m.groups[2])
+		return r.render(name, offset)
+	}
+	if r.plain {
+		return r.render(name, offset)
+	}
+	return "[" + r.render(name, offset) + "](tg://user?id=" + m.groups[2] + ")"
+}
+
+// renderCustomEmoji renderiza ![👍](tg://emoji?id=...). O emoji entre
+// colchetes é o fallback exibido quando o emoji personalizado não está
+// disponível, e é o que sobra se o ID for inválido.
+func (r *inlineRenderer) renderCustomEmoji(m inlineMatch) string {
+	emoji := strings.TrimSpace(m.groups[1])
+	if emoji == "" {
+		return r.escapeText(m.groups[0], m.offsets[0])
+	}
+	if !isTelegramID(m.groups[2]) {
+		r.reportf(m.offsets[2], types.DiagInvalidEmojiID,
+			"invalid custom emoji ID %q; only the fallback emoji was kept", m.groups[2])
+		return r.escape(emoji, EscapeText)
+	}
+	if r.plain {
+		return emoji
+	}
+	return "![" + r.escape(emoji, EscapeText) + "](tg://emoji?id=" + m.groups[2] + ")"
+}
+
+func isTelegramID(id string) bool {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return err == nil && n > 0
+}
+
+func (r *inlineRenderer) renderLink(m inlineMatch) string {
+	if strings.TrimSpace(m.groups[1]) == "" || strings.TrimSpace(m.groups[2]) == "" {
+		return r.escapeText(m.groups[0], m.offsets[0])
+	}
+
+	// \X na URL é um caractere literal, como em \) dentro do destino
+	url := utils.EscapedCharPattern.ReplaceAllString(m.groups[2], "$1")
+	if policy := r.config.LinkPolicy; policy != nil {
+		resolved, ok := policy.Resolve(url)
+		if !ok {
+			r.reportf(m.offsets[2], types.DiagLinkDisallowed,
+				"link URL %q was rejected by the link policy", url)
+			return r.renderDisallowedLink(m, policy.OnDisallowed)
+		}
+		url = resolved
+	}
+
+	text := r.render(m.groups[1], m.offsets[1])
+	if r.plain {
+		return text + " (" + url + ")"
+	}
+	return "[" + text + "](" + Escape(url, EscapeLinkURL) + ")"
+}
+
+func (r *inlineRenderer) renderDisallowedLink(m inlineMatch, action types.LinkAction) string {
+	url := utils.EscapedCharPattern.ReplaceAllString(m.groups[2], "$1")
+	switch action {
+	case types.LinkDrop:
+		return ""
+	case types.LinkShowURLAsCode:
+		text := r.render(m.groups[1], m.offsets[1])
+		if r.plain {
+			return text + " (" + url + ")"
+		}
+		return text + " \\(`" + Escape(url, EscapeCode) + "`\\)"
+	default:
+		return r.render(m.groups[1], m.offsets[1])
+	}
+}
+
+func (r *inlineRenderer) renderBold(m inlineMatch) string {
+	switch {
+	case m.groups[1] != "":
+		return r.wrap("*", m, 2)
+	case m.groups[3] != "":
+		return r.wrap("*", m, 4)
+	default:
+		return r.wrap("_", m, 6)
+	}
+}
+
+func (r *inlineRenderer) renderStrikethrough(m inlineMatch) string {
+	return r.wrap("~", m, 1)
+}
+
+func (r *inlineRenderer) renderItalic(m inlineMatch) string {
+	return r.wrap("_", m, 2)
+}
+
+func (r *inlineRenderer) reportf(offset int, code, format string, args ...interface{}) {
+	if r.report {
+		report(r.config, offset, types.SeverityWarning, code, format, args...)
+	}
+}
+
+// RenderInline converte a formatação inline do Markdown para o modo de saída
+// configurado, escapando cada trecho conforme o seu contexto. Extensões inline
+// podem usá-la para renderizar o texto que envolvem.
+func RenderInline(text string, config *types.Config) string {
+	return newInlineRenderer(config).render(text, 0)
+}
+
+// renderInlineAt renderiza um trecho do conteúdo do bloco que começa em
+// offset, relatando diagnósticos com a posição correta
+func renderInlineAt(text string, offset int, config *types.Config) string {
+	r := newInlineRenderer(config)
+	r.report = true
+	return r.render(text, offset)
+}
@@ -0,0 +1,62 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestMentionsAndCustomEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		mode  types.OutputMode
+		want  string
+	}{
+		{"mention", "oi [João S.](tg://user?id=123)", types.OutputMarkdownV2, `oi [João S\.](tg://user?id=123)`},
+		{"mention with formatting", "[**Ana**](tg://user?id=42)", types.OutputMarkdownV2, "[*Ana*](tg://user?id=42)"},
+		{"mention invalid id", "[Ana](tg://user?id=abc)", types.OutputMarkdownV2, "Ana"},
+		{"mention zero id", "[Ana](tg://user?id=0)", types.OutputMarkdownV2, "Ana"},
+		{"mention empty name", "[](tg://user?id=1)", types.OutputMarkdownV2, `\[\]\(tg://user?id\=1\)`},
+		{"mention bypasses link policy", "[Ana](tg://user?id=7)", types.OutputMarkdownV2, "[Ana](tg://user?id=7)"},
+		{"custom emoji", "![👍](tg://emoji?id=5368324170671202286)", types.OutputMarkdownV2, "![👍](tg://emoji?id=5368324170671202286)"},
+		{"custom emoji invalid id", "![👍](tg://emoji?id=x)", types.OutputMarkdownV2, "👍"},
+		{"custom emoji reserved fallback", "![-](tg://emoji?id=1)", types.OutputMarkdownV2, `![\-](tg://emoji?id=1)`},
+		{"plain mention", "oi [João S.](tg://user?id=123)", types.OutputPlainText, "oi João S."},
+		{"plain custom emoji", "![👍](tg://emoji?id=1) ok", types.OutputPlainText, "👍 ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.OutputMode = tt.mode
+			config.LinkPolicy = &types.LinkPolicy{AllowedSchemes: []string{"https"}}
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainTextOutput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"formatting removed", "**negrito**, _itálico_ e ~~riscado~~", "negrito, itálico e riscado"},
+		{"no escapes", "Fim. (ok) 1-2", "Fim. (ok) 1-2"},
+		{"author escapes removed", `\*literal\*`, "*literal*"},
+		{"inline code", "use `a_b`", "use a_b"},
+		{"link", "[site](https://ex.com)", "site (https://ex.com)"},
+		{"unbalanced marker", "2 * 3", "2 * 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.OutputMode = types.OutputPlainText
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package formatter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+	"github.com/sshturbo/GoTeleMD/pkg/utils"
+)
+
+var (
+	// Um link de botão: [texto](button:URL) ou [texto](callback:dados). O
+	// destino aceita parênteses como a URL de um link comum.
+	buttonPattern = regexp.MustCompile(`\[((?:\\.|[^\]\\])+)\]\((button|callback):(` + utils.LinkURLPattern + `)\)`)
+	// Uma linha só com links de botão
+	buttonLinePattern = regexp.MustCompile(`^[ \t]*(?:\[(?:\\.|[^\]\\])+\]\((?:button|callback):` + utils.LinkURLPattern + `\)[ \t]*)+$`)
+)
+
+// ExtractButtons remove do fim do Markdown o parágrafo formado só por links de
+// botão e o devolve como teclado inline, com uma fileira por linha. Botões
+// inválidos são descartados e relatados ao reporter, se houver, com a posição
+// em bytes no Markdown recebido. Sem esse parágrafo, o Markdown volta
+// inalterado e o teclado é nil.
+func ExtractButtons(input string, config *types.Config, reporter types.DiagnosticReporter) (string, *types.InlineKeyboardMarkup) {
+	lines := strings.Split(strings.TrimRight(input, " \t\r\n"), "\n")
+	first := len(lines)
+	for first > 0 && strings.TrimSpace(lines[first-1]) != "" {
+		if !buttonLinePattern.MatchString(strings.TrimRight(lines[first-1], "\r")) {
+			return input, nil
+		}
+		first--
+	}
+	if first == len(lines) {
+		return input, nil
+	}
+
+	// Um parágrafo dentro de um bloco de código não aberto no fim não conta
+	fences := 0
+	for _, line := range lines[:first] {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fences++
+		}
+	}
+	if fences%2 == 1 {
+		return input, nil
+	}
+
+	offset := 0
+	for _, line := range lines[:first] {
+		offset += len(line) + 1
+	}
+
+	keyboard := &types.InlineKeyboardMarkup{}
+	for _, line := range lines[first:] {
+		var row []types.InlineKeyboardButton
+		for _, match := range buttonPattern.FindAllStringSubmatchIndex(line, -1) {
+			// Os escapes de Markdown do texto e do destino são removidos
+			text := utils.EscapedCharPattern.ReplaceAllString(strings.TrimSpace(line[match[2]:match[3]]), "$1")
+			kind := line[match[4]:match[5]]
+			target := utils.EscapedCharPattern.ReplaceAllString(line[match[6]:match[7]], "$1")
+			button, problem := newButton(text, kind, target, config)
+			if problem != "" {
+				if reporter != nil {
+					reporter.Report(offset+match[0], types.SeverityWarning, types.DiagButtonDropped, problem)
+				}
+				continue
+			}
+			row = append(row, button)
+		}
+		if len(row) > 0 {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+		}
+		offset += len(line) + 1
+	}
+
+	text := strings.TrimRight(strings.Join(lines[:first], "\n"), " \t\r\n")
+	if len(keyboard.InlineKeyboard) == 0 {
+		return text, nil
+	}
+	return text, keyboard
+}
+
+// newButton monta o botão ou devolve o motivo para descartá-lo
+func newButton(text, kind, target string, config *types.Config) (types.InlineKeyboardButton, string) {
+	button := types.InlineKeyboardButton{Text: text}
+	if text == "" {
+		return button, "button has no text"
+	}
+
+	if kind == "callback" {
+		if target == "" || len(target) > types.MaxCallbackDataSize {
+			return button, fmt.Sprintf("callback data of button %q must have 1 to %d bytes, got %d",
+				text, types.MaxCallbackDataSize, len(target))
+		}
+		button.CallbackData = target
+		return button, ""
+	}
+
+	resolved := target
+	if config.LinkPolicy != nil {
+		var ok bool
+		if resolved, ok = config.LinkPolicy.Resolve(target); !ok {
+			return button, fmt.Sprintf("URL of button %q is not allowed by the link policy", text)
+		}
+	}
+	// Botões da Bot API só aceitam esses esquemas
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "tg") {
+		return button, fmt.Sprintf("URL of button %q must use http, https or tg", text)
+	}
+	button.URL = resolved
+	return button, ""
+}
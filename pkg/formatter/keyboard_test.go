@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+type recordingReporter struct {
+	offsets []int
+	codes   []string
+}
+
+func (r *recordingReporter) Report(offset int, _ types.Severity, code, _ string) {
+	r.offsets = append(r.offsets, offset)
+	r.codes = append(r.codes, code)
+}
+
+// keyboardString resume o teclado como "[texto>destino ...] [...]"
+func keyboardString(keyboard *types.InlineKeyboardMarkup) string {
+	if keyboard == nil {
+		return "nil"
+	}
+	var rows []string
+	for _, row := range keyboard.InlineKeyboard {
+		var buttons []string
+		for _, button := range row {
+			buttons = append(buttons, button.Text+">"+button.URL+button.CallbackData)
+		}
+		rows = append(rows, "["+strings.Join(buttons, " ")+"]")
+	}
+	return strings.Join(rows, " ")
+}
+
+func TestExtractButtons(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantText string
+		want     string
+		dropped  int
+	}{
+		{"no buttons", "Olá [site](https://ex.com)", "Olá [site](https://ex.com)", "nil", 0},
+		{"url button", "Olá\n\n[Abrir](button:https://ex.com/a)", "Olá", "[Abrir>https://ex.com/a]", 0},
+		{"callback button", "Olá\n\n[Repetir](callback:retry:1)", "Olá", "[Repetir>retry:1]", 0},
+		{"rows", "Olá\n\n[A](callback:a) [B](callback:b)\n[C](button:tg://resolve?domain=bot)\n",
+			"Olá", "[A>a B>b] [C>tg://resolve?domain=bot]", 0},
+		{"escaped text", "Olá\n\n[a\\_b \\[x\\]](callback:x)", "Olá", "[a_b [x]>x]", 0},
+		{"url with parentheses", "Olá\n\n[Go](button:https://pt.wikipedia.org/wiki/Go_(linguagem))", "Olá",
+			"[Go>https://pt.wikipedia.org/wiki/Go_(linguagem)]", 0},
+		{"escaped parenthesis in url", "Olá\n\n[A](button:https://ex.com/a\\)b) [B](callback:b)", "Olá",
+			"[A>https://ex.com/a)b B>b]", 0},
+		{"only buttons", "[A](callback:a)", "", "[A>a]", 0},
+		{"trailing whitespace", "Olá\n\n  [A](callback:a)  \r\n\n", "Olá", "[A>a]", 0},
+		{"mixed line", "Olá\n\nVeja [A](callback:a)", "Olá\n\nVeja [A](callback:a)", "nil", 0},
+		{"not last paragraph", "[A](callback:a)\n\nFim", "[A](callback:a)\n\nFim", "nil", 0},
+		{"inside unclosed code block", "```\n\n[A](callback:a)", "```\n\n[A](callback:a)", "nil", 0},
+		{"after closed code block", "```\nx\n```\n\n[A](callback:a)", "```\nx\n```", "[A>a]", 0},
+		{"disallowed url", "Olá\n\n[A](button:javascript:x) [B](callback:b)", "Olá", "[B>b]", 1},
+		{"unsupported scheme", "Olá\n\n[A](button:mailto:a@ex.com)", "Olá", "nil", 1},
+		{"empty callback", "Olá\n\n[A](callback:)", "Olá", "nil", 1},
+		{"long callback", "Olá\n\n[A](callback:" + strings.Repeat("x", types.MaxCallbackDataSize+1) + ")", "Olá", "nil", 1},
+		{"blank text", "Olá\n\n[ ](callback:a)", "Olá", "nil", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			text, keyboard := ExtractButtons(tt.input, types.DefaultConfig(), reporter)
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if got := keyboardString(keyboard); got != tt.want {
+				t.Errorf("keyboard = %s, want %s", got, tt.want)
+			}
+			if len(reporter.codes) != tt.dropped {
+				t.Errorf("diagnostics = %v, want %d", reporter.codes, tt.dropped)
+			}
+			for _, code := range reporter.codes {
+				if code != types.DiagButtonDropped {
+					t.Errorf("diagnostic code = %q, want %q", code, types.DiagButtonDropped)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractButtonsReportsOffset(t *testing.T) {
+	input := "Olá\n\n[A](callback:a) [B](callback:)"
+	reporter := &recordingReporter{}
+	ExtractButtons(input, types.DefaultConfig(), reporter)
+	want := strings.Index(input, "[B]")
+	if fmt.Sprint(reporter.offsets) != fmt.Sprint([]int{want}) {
+		t.Errorf("offsets = %v, want [%d]", reporter.offsets, want)
+	}
+}
@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// lineShift indica que, a partir da posição at no texto transformado, as
+// posições no texto original estão shift bytes à frente
+type lineShift struct {
+	at    int
+	shift int
+}
+
+// applyLineBreaks aplica as regras de quebra de linha da configuração a um
+// bloco de parágrafos:
+//
+//   - uma linha terminada em \ ou em dois ou mais espaços, seguida de outra
+//     linha do mesmo parágrafo, é uma quebra forçada: o marcador é removido e
+//     a quebra é sempre mantida
+//   - com StrictLineBreaks desligado, as demais quebras dentro de um parágrafo
+//     viram um espaço, como no CommonMark
+//   - com PreserveEmptyLines desligado, linhas em branco seguidas viram uma só
+//
+// Os bytes removidos ficam registrados para que os diagnósticos continuem
+// apontando para a posição original.
+func applyLineBreaks(text string, config *types.Config) (string, []lineShift) {
+	lines := strings.Split(text, "\n")
+
+	var out strings.Builder
+	out.Grow(len(text))
+	var shifts []lineShift
+	dropped := 0
+	drop := func(n int) {
+		if n > 0 {
+			dropped += n
+			shifts = append(shifts, lineShift{at: out.Len(), shift: dropped})
+		}
+	}
+
+	softBreak := false
+	for i, line := range lines {
+		last := i == len(lines)-1
+		blank := strings.TrimSpace(line) == ""
+
+		if blank && i > 0 && strings.TrimSpace(lines[i-1]) == "" && !config.PreserveEmptyLines {
+			drop(len(line) + 1)
+			continue
+		}
+
+		// Depois de uma quebra suave, a indentação da linha não faz parte do
+		// parágrafo
+		if softBreak {
+			content := strings.TrimLeft(line, " \t")
+			drop(len(line) - len(content))
+			line = content
+		}
+
+		nextBlank := last || strings.TrimSpace(lines[i+1]) == ""
+		content, hard := line, false
+		if !blank && !nextBlank {
+			content, hard = trimHardBreak(line)
+		}
+		out.WriteString(content)
+		drop(len(line) - len(content))
+
+		if last {
+			break
+		}
+		softBreak = !config.StrictLineBreaks && !hard && !blank && !nextBlank
+		if softBreak {
+			out.WriteByte(' ')
+		} else {
+			out.WriteByte('\n')
+		}
+	}
+	return out.String(), shifts
+}
+
+// trimHardBreak remove o marcador de quebra forçada do fim da linha. Uma barra
+// invertida só é marcador se não estiver ela mesma escapada.
+func trimHardBreak(line string) (string, bool) {
+	if trimmed := strings.TrimRight(line, "\\"); (len(line)-len(trimmed))%2 == 1 {
+		return line[:len(line)-1], true
+	}
+	if trimmed := strings.TrimRight(line, " "); len(line)-len(trimmed) >= 2 {
+		return trimmed, true
+	}
+	return line, false
+}
+
+// shiftedReporter corrige as posições relatadas sobre o texto transformado
+// por applyLineBreaks antes de repassá-las
+type shiftedReporter struct {
+	reporter types.DiagnosticReporter
+	shifts   []lineShift
+}
+
+func (r *shiftedReporter) Report(offset int, severity types.Severity, code, message string) {
+	if offset >= 0 {
+		i := sort.Search(len(r.shifts), func(i int) bool { return r.shifts[i].at > offset })
+		if i > 0 {
+			offset += r.shifts[i-1].shift
+		}
+	}
+	r.reporter.Report(offset, severity, code, message)
+}
+
+// withShifts devolve uma cópia da configuração cujo Reporter considera os
+// bytes removidos por applyLineBreaks
+func withShifts(config *types.Config, shifts []lineShift) *types.Config {
+	if config.Reporter == nil || len(shifts) == 0 {
+		return config
+	}
+	shifted := *config
+	shifted.Reporter = &shiftedReporter{reporter: config.Reporter, shifts: shifts}
+	return &shifted
+}
@@ -0,0 +1,73 @@
+package formatter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestApplyLineBreaks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		strict   bool
+		preserve bool
+		want     string
+	}{
+		{"strict keeps breaks", "a\nb", true, true, "a\nb"},
+		{"soft break joins lines", "a\nb\nc", false, true, "a b c"},
+		{"soft break drops indentation", "a\n   b", false, true, "a b"},
+		{"paragraphs stay apart", "a\n\nb", false, true, "a\n\nb"},
+		{"hard break with spaces", "a  \nb", false, true, "a\nb"},
+		{"hard break with backslash", "a\\\nb", false, true, "a\nb"},
+		{"escaped backslash is not a break", "a\\\\\nb", false, true, "a\\\\ b"},
+		{"single trailing space", "a \nb", true, true, "a \nb"},
+		{"hard break in strict mode", "a  \nb", true, true, "a\nb"},
+		{"marker before blank line is kept", "a  \n\nb", true, true, "a  \n\nb"},
+		{"marker on last line is kept", "a\\", true, true, "a\\"},
+		{"empty lines preserved", "a\n\n\n\nb", true, true, "a\n\n\n\nb"},
+		{"empty lines collapsed", "a\n\n\n\nb", true, false, "a\n\nb"},
+		{"whitespace lines collapsed", "a\n\n  \n\t\nb", true, false, "a\n\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.StrictLineBreaks = tt.strict
+			config.PreserveEmptyLines = tt.preserve
+			if got, _ := applyLineBreaks(tt.input, config); got != tt.want {
+				t.Errorf("applyLineBreaks(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLineBreaksShifts(t *testing.T) {
+	config := types.DefaultConfig()
+	config.StrictLineBreaks = false
+
+	got, shifts := applyLineBreaks("um  \ndois\n   tres", config)
+	if got != "um\ndois tres" {
+		t.Fatalf("applyLineBreaks = %q, want %q", got, "um\ndois tres")
+	}
+	want := []lineShift{{at: 2, shift: 2}, {at: 8, shift: 5}}
+	if !reflect.DeepEqual(shifts, want) {
+		t.Errorf("shifts = %+v, want %+v", shifts, want)
+	}
+}
+
+func TestLineBreakDiagnosticsUseOriginalPositions(t *testing.T) {
+	config := types.DefaultConfig()
+	config.StrictLineBreaks = false
+
+	result, err := ConvertMarkdownDetailed("um  \ndois\n   tres * x", config)
+	if err != nil {
+		t.Fatalf("ConvertMarkdownDetailed error: %v", err)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("diagnostics = %+v, want exactly one", result.Diagnostics)
+	}
+	if d := result.Diagnostics[0]; d.Line != 3 || d.Column != 9 {
+		t.Errorf("diagnostic at %d:%d, want 3:9", d.Line, d.Column)
+	}
+}
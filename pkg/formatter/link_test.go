@@ -0,0 +1,65 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestLinkPolicyActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *types.LinkPolicy
+		input  string
+		want   string
+	}{
+		{"no policy", nil, "[x](javascript:alert)", "[x](javascript:alert)"},
+		{"allowed", types.DefaultLinkPolicy(), "[x](https://ex.com)", "[x](https://ex.com)"},
+		{"keep text", &types.LinkPolicy{AllowedSchemes: []string{"https"}}, "ver [x.y](ftp://ex.com) agora", `ver x\.y agora`},
+		{"drop", &types.LinkPolicy{AllowedSchemes: []string{"https"}, OnDisallowed: types.LinkDrop}, "ver [x](ftp://ex.com) agora", "ver  agora"},
+		{"show url as code", &types.LinkPolicy{AllowedSchemes: []string{"https"}, OnDisallowed: types.LinkShowURLAsCode}, "[x](ftp://ex.com/`a`)", "x \\(`ftp://ex.com/\\`a\\``\\)"},
+		{"url escaped", types.DefaultLinkPolicy(), `[x](https://ex.com/a\b)`, `[x](https://ex.com/a\\b)`},
+		{"balanced parentheses", types.DefaultLinkPolicy(), "[w](https://ex.com/Go_(lang))", `[w](https://ex.com/Go_(lang\))`},
+		{"several balanced groups", types.DefaultLinkPolicy(), "[x](https://ex.com/a(b)c(d)) e", `[x](https://ex.com/a(b\)c(d\)) e`},
+		{"escaped closing parenthesis", types.DefaultLinkPolicy(), `[x](https://ex.com/a\)b)`, `[x](https://ex.com/a\)b)`},
+		{"escaped opening parenthesis", types.DefaultLinkPolicy(), `[x](https://ex.com/a\(b)`, "[x](https://ex.com/a(b)"},
+		{"link inside parentheses", types.DefaultLinkPolicy(), "([x](https://ex.com)) fim", `\([x](https://ex.com)\) fim`},
+		{"disallowed url with parentheses", types.DefaultLinkPolicy(), "[x](javascript:alert(1)) fim", "x fim"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.LinkPolicy = tt.policy
+			if got := ProcessTextWithConfig(tt.input, config); got != tt.want {
+				t.Errorf("ProcessTextWithConfig(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkPolicyAtSafetyLevelNone(t *testing.T) {
+	config := types.DefaultConfig()
+	config.SafetyLevel = 0
+	config.LinkPolicy = &types.LinkPolicy{AllowedSchemes: []string{"https"}}
+
+	got := ProcessTextWithConfig("[ok](https://ex.com) [ruim](javascript:x)", config)
+	if want := "[ok](https://ex.com) ruim"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLegacyWrappersUseDefaultConfig(t *testing.T) {
+	config := types.DefaultConfig()
+	config.SafetyLevel = 2
+	input := "# Título *1*"
+	if got, want := ProcessTitle(input, 2), ProcessTitleWithConfig(input, config); got != want {
+		t.Errorf("ProcessTitle = %q, want %q", got, want)
+	}
+
+	lines := []string{"| a | b |", "|---|---|", "| 1 | 2 |"}
+	config = types.DefaultConfig()
+	config.AlignTableColumns = false
+	if got, want := ConvertTable(lines, false, false), ConvertTableWithConfig(lines, config); got != want {
+		t.Errorf("ConvertTable = %q, want %q", got, want)
+	}
+}
@@ -1,38 +1,98 @@
 package formatter
 
 import (
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/sshturbo/GoTeleMD/internal"
 	"github.com/sshturbo/GoTeleMD/pkg/types"
-	"github.com/sshturbo/GoTeleMD/pkg/utils"
 )
 
-func RenderBlock(b internal.Block, config *types.Config) string {
-	renderStart := time.Now()
-	defer func() {
-		utils.LogPerformance("renderBlock "+strconv.Itoa(int(b.Type)), time.Since(renderStart))
-	}()
-
-	utils.LogDebug("Renderizando bloco tipo: %v", b.Type)
+// Renderizadores embutidos, expostos para que renderizadores personalizados
+// possam delegar a eles
+var (
+	TextRenderer  types.BlockRenderer = types.BlockRendererFunc(renderTextBlock)
+	CodeRenderer  types.BlockRenderer = types.BlockRendererFunc(renderCodeBlockContent)
+	TableRenderer types.BlockRenderer = types.BlockRendererFunc(renderTableBlock)
+	TitleRenderer types.BlockRenderer = types.BlockRendererFunc(renderTitleBlock)
+	ListRenderer  types.BlockRenderer = types.BlockRendererFunc(renderListBlock)
+	QuoteRenderer types.BlockRenderer = types.BlockRendererFunc(renderQuoteBlock)
+)
 
-	switch b.Type {
+// DefaultRenderer devolve o renderizador embutido de um tipo de bloco. Tipos
+// desconhecidos são renderizados como texto.
+func DefaultRenderer(blockType internal.BlockType) types.BlockRenderer {
+	switch blockType {
 	case internal.BlockCode:
-		return b.Content
-	case internal.BlockText:
-		return ProcessText(strings.TrimSpace(b.Content), config.SafetyLevel)
+		return CodeRenderer
 	case internal.BlockTable:
-		lines := strings.Split(b.Content, "\n")
-		return ConvertTable(lines, config.AlignTableColumns, config.IgnoreTableSeparator)
+		return TableRenderer
 	case internal.BlockTitle:
-		return ProcessTitle(b.Content, config.SafetyLevel)
+		return TitleRenderer
 	case internal.BlockList:
-		return ProcessList(b.Content, config.SafetyLevel)
+		return ListRenderer
 	case internal.BlockQuote:
-		return ProcessQuote(b.Content, config.SafetyLevel)
+		return QuoteRenderer
 	default:
-		return ProcessText(strings.TrimSpace(b.Content), config.SafetyLevel)
+		return TextRenderer
+	}
+}
+
+// RenderBlock renderiza um bloco com o renderizador registrado para o seu tipo
+// ou com o embutido. O renderizador recebe uma cópia da configuração em que
+// SafetyLevel já é o nível do tipo do bloco.
+func RenderBlock(b internal.Block, config *types.Config) string {
+	if len(config.BlockSafetyLevels) > 0 {
+		blockConfig := *config
+		blockConfig.SafetyLevel = config.SafetyLevelFor(b.Type)
+		blockConfig.BlockSafetyLevels = nil
+		config = &blockConfig
+	}
+
+	if renderer, ok := config.Renderers[b.Type]; ok && renderer != nil {
+		return renderer.RenderBlock(b, config)
+	}
+	return DefaultRenderer(b.Type).RenderBlock(b, config)
+}
+
+func renderTextBlock(b internal.Block, config *types.Config) string {
+	text, shifts := applyLineBreaks(strings.TrimSpace(b.Content), config)
+	return ProcessTextWithConfig(text, withShifts(config, shifts))
+}
+
+func renderCodeBlockContent(b internal.Block, config *types.Config) string {
+	if config.OutputMode == types.OutputPlainText {
+		return plainCodeBlock(b.Content)
+	}
+	if config.SafetyLevelFor(internal.BlockCode) == internal.SAFETYLEVELNONE {
+		return b.Content
+	}
+	if !isClosedCodeBlock(b.Content) {
+		report(config, 0, types.SeverityWarning, types.DiagUnclosedCodeBlock,
+			"code block without a closing fence was closed automatically")
 	}
+	return renderCodeBlock(b.Content)
+}
+
+func renderTableBlock(b internal.Block, config *types.Config) string {
+	lines := strings.Split(b.Content, "\n")
+	return ConvertTableWithConfig(lines, config)
+}
+
+func renderTitleBlock(b internal.Block, config *types.Config) string {
+	return ProcessTitleWithConfig(b.Content, config)
+}
+
+func renderListBlock(b internal.Block, config *types.Config) string {
+	return ProcessListWithConfig(b.Content, config)
+}
+
+func renderQuoteBlock(b internal.Block, config *types.Config) string {
+	return ProcessQuoteWithConfig(b.Content, config)
+}
+
+// renderFallback renderiza o texto original do bloco sem nenhuma formatação,
+// com todos os caracteres reservados escapados. É usado quando o renderizador
+// do bloco falha.
+func renderFallback(b internal.Block, config *types.Config) string {
+	return escapeFor(config, strings.TrimSpace(b.Content), EscapeText)
 }
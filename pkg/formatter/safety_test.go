@@ -0,0 +1,53 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestBlockSafetyLevels(t *testing.T) {
+	input := "> **citação** x.\n\n| **a** | b |\n|---|---|\n| 1 | 2 |\n\n**texto** 1."
+	tests := []struct {
+		name   string
+		levels map[types.BlockType]int
+		want   []string
+	}{
+		{"base level only", nil, []string{"> *citação* x\\.", "•  *a*", "*texto* 1\\."}},
+		{"strict quote", map[types.BlockType]int{types.BlockQuote: 2}, []string{`\> \*\*citação\*\* x\.`, "•  *a*", "*texto* 1\\."}},
+		{"strict table", map[types.BlockType]int{types.BlockTable: 2}, []string{"> *citação* x\\.", `•  \*\*a\*\* \| b`, "*texto* 1\\."}},
+		{"text without escapes", map[types.BlockType]int{types.BlockText: 0}, []string{"> *citação* x\\.", "*texto* 1."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.BlockSafetyLevels = tt.levels
+			got, err := ConvertMarkdown(input, config)
+			if err != nil {
+				t.Fatalf("ConvertMarkdown error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("ConvertMarkdown() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderBlockPassesBlockLevel(t *testing.T) {
+	var seen int
+	config := types.DefaultConfig()
+	config.BlockSafetyLevels = map[types.BlockType]int{types.BlockQuote: 2}
+	config.Renderers = map[types.BlockType]types.BlockRenderer{
+		types.BlockQuote: types.BlockRendererFunc(func(b types.Block, c *types.Config) string {
+			seen = c.SafetyLevel
+			return b.Content
+		}),
+	}
+	RenderBlock(types.Block{Type: types.BlockQuote, Content: "> x"}, config)
+	if seen != 2 {
+		t.Errorf("renderer saw SafetyLevel %d, want 2", seen)
+	}
+}
@@ -2,21 +2,37 @@ package formatter
 
 import (
 	"strings"
-	"unicode"
 	"unicode/utf8"
 
+	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
 	"github.com/sshturbo/GoTeleMD/pkg/utils"
 )
 
-func ConvertTable(lines []string, align, ignoreSeparators bool) string {
+// ConvertTableWithConfig converte as linhas de uma tabela com a configuração
+// informada
+func ConvertTableWithConfig(lines []string, config *types.Config) string {
+	align := config.AlignTableColumns
+	ignoreSeparators := config.IgnoreTableSeparator
 	var rows [][]string
+	// Posição de cada linha e de cada célula no conteúdo do bloco, para os
+	// diagnósticos
+	var rowOffsets []int
+	var cellOffsets [][]int
 	maxCols := 0
 	var alignments []string
 
+	lineStart := 0
 	for i, line := range lines {
+		original, offset := line, lineStart
+		lineStart += len(line) + 1
+
 		if utils.SeparatorLine.MatchString(line) {
 			if !ignoreSeparators && i == 1 && i < len(lines)-1 {
 				alignments = parseTableAlignment(line)
+			} else if i != 1 {
+				report(config, offset, types.SeverityWarning, types.DiagTableRowDropped,
+					"table row with only separators was dropped")
 			}
 			continue
 		}
@@ -51,20 +67,37 @@ func ConvertTable(lines []string, align, ignoreSeparators bool) string {
 		cols = append(cols, currentCol.String())
 
 		var clean []string
+		var offsets []int
 		for _, col := range cols {
-			clean = append(clean, ProcessInlineFormatting(strings.TrimSpace(col)))
+			cell := strings.TrimSpace(col)
+			cellOffset := offset
+			if idx := strings.Index(original, cell); idx >= 0 && cell != "" {
+				cellOffset += idx
+			}
+			clean = append(clean, cell)
+			offsets = append(offsets, cellOffset)
 		}
 		if len(clean) > 0 {
 			rows = append(rows, clean)
+			rowOffsets = append(rowOffsets, offset)
+			cellOffsets = append(cellOffsets, offsets)
 			if len(clean) > maxCols {
 				maxCols = len(clean)
 			}
 		}
 	}
 
+	for i, row := range rows {
+		if len(row) < maxCols {
+			report(config, rowOffsets[i], types.SeverityInfo, types.DiagTableRowPadded,
+				"table row has %d of %d columns; missing cells were left empty", len(row), maxCols)
+		}
+	}
+
+	safetyLevel := safetyLevelFor(config, internal.BlockTable)
 	alignments = normalizeAlignments(alignments, maxCols)
-	colWidths := calculateColumnWidths(rows, maxCols)
-	return formatTable(rows, colWidths, alignments, align)
+	colWidths := calculateColumnWidths(rows, maxCols, safetyLevel)
+	return formatTable(rows, cellOffsets, colWidths, alignments, align, safetyLevel, config)
 }
 
 func normalizeAlignments(alignments []string, maxCols int) []string {
@@ -82,12 +115,12 @@ func normalizeAlignments(alignments []string, maxCols int) []string {
 	return alignments
 }
 
-func calculateColumnWidths(rows [][]string, maxCols int) []int {
+func calculateColumnWidths(rows [][]string, maxCols, safetyLevel int) []int {
 	colWidths := make([]int, maxCols)
 	for _, row := range rows {
 		for i := 0; i < maxCols; i++ {
 			if i < len(row) {
-				width := utf8.RuneCountInString(row[i])
+				width := cellWidth(row[i], safetyLevel)
 				if width > colWidths[i] {
 					colWidths[i] = width
 				}
@@ -97,48 +130,73 @@ func calculateColumnWidths(rows [][]string, maxCols int) []int {
 	return colWidths
 }
 
-func formatTable(rows [][]string, colWidths []int, alignments []string, align bool) string {
+func formatTable(rows [][]string, cellOffsets [][]int, colWidths []int, alignments []string, align bool, safetyLevel int, config *types.Config) string {
 	var builder strings.Builder
 	builder.WriteString("\n")
 
-	for _, row := range rows {
+	for r, row := range rows {
 		var formattedColumns []string
 		for i := 0; i < len(colWidths); i++ {
-			var col string
+			var raw string
+			offset := -1
 			if i < len(row) {
-				col = row[i]
-			} else {
-				col = ""
+				raw = row[i]
+				offset = cellOffsets[r][i]
 			}
 
+			col := renderCell(raw, offset, safetyLevel, config)
 			if align {
-				col = alignColumn(col, colWidths[i], getAlignType(alignments, i))
+				col = alignColumn(col, cellWidth(raw, safetyLevel), colWidths[i], getAlignType(alignments, i))
 			}
 			formattedColumns = append(formattedColumns, col)
 		}
 
-		line := formatTableRow(formattedColumns, align)
+		line := formatTableRow(formattedColumns, align, escapeFor(config, "|", EscapeText))
 		builder.WriteString(line + "\n")
 	}
 
 	return strings.TrimRight(builder.String(), "\n")
 }
 
-func alignColumn(col string, width int, alignType string) string {
+// renderCell renderiza o conteúdo de uma célula conforme o nível de segurança
+// da tabela. No nível STRICT a célula é exibida literalmente.
+func renderCell(raw string, offset, safetyLevel int, config *types.Config) string {
+	if safetyLevel >= internal.SAFETYLEVELSTRICT {
+		return escapeFor(config, raw, EscapeText)
+	}
+	return formatInline(raw, offset, safetyLevel, config)
+}
+
+// cellWidth calcula a largura visível de uma célula, sem contar os escapes
+// que serão adicionados na renderização. No nível STRICT os marcadores de
+// formatação aparecem na mensagem e também contam.
+func cellWidth(cell string, safetyLevel int) int {
+	if safetyLevel >= internal.SAFETYLEVELSTRICT {
+		return utf8.RuneCountInString(cell)
+	}
+	return utf8.RuneCountInString(ProcessInlineFormatting(cell))
+}
+
+// alignColumn alinha uma célula já renderizada usando a largura visível do
+// conteúdo original
+func alignColumn(col string, colWidth, width int, alignType string) string {
 	if width < 5 {
 		width = 5
 	}
+	pad := width - colWidth
+	if pad < 0 {
+		pad = 0
+	}
 
 	switch alignType {
 	case "c":
-		pad := width - utf8.RuneCountInString(col)
 		leftPad := pad / 2
 		rightPad := pad - leftPad
 		return strings.Repeat(" ", leftPad) + col + strings.Repeat(" ", rightPad)
 	case "r":
-		return strings.Repeat(" ", width-utf8.RuneCountInString(col)) + col
+		return strings.Repeat(" ", pad) + col
 	default: // "l"
-		return col + strings.Repeat(" ", width-utf8.RuneCountInString(col))
+		return col + strings.Repeat(" ", pad)
 	}
 }
 
@@ -149,57 +207,14 @@ func getAlignType(alignments []string, index int) string {
 	return "l"
 }
 
-func formatTableRow(columns []string, align bool) string {
-	// Escapa os caracteres | em cada coluna
-	escapedColumns := make([]string, len(columns))
-	specialChars := []string{"[", "]", "(", ")", ">", "#", "+", "-", "=", "|", "{", "}", ".", "!", "\\"}
-
-	for i, col := range columns {
-		// Primeiro escapa os pontos no conteúdo
-		escapedCol := col
-		runes := []rune(escapedCol)
-		var result strings.Builder
-		lastWasDigit := false
-
-		for i, r := range runes {
-			c := string(r)
-
-			if unicode.IsDigit(r) {
-				lastWasDigit = true
-				result.WriteRune(r)
-				continue
-			}
-
-			isSpecial := false
-			for _, special := range specialChars {
-				if c == special {
-					if c == "." && lastWasDigit && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
-						// Não escapa ponto entre números
-						isSpecial = false
-						break
-					}
-					result.WriteString("\\" + c)
-					isSpecial = true
-					break
-				}
-			}
-
-			if !isSpecial {
-				result.WriteRune(r)
-			}
-			lastWasDigit = false
-		}
-
-		escapedCol = result.String()
-		escapedColumns[i] = escapedCol
-	}
-
+func formatTableRow(columns []string, align bool, separator string) string {
 	prefix := "•  "
 	if !align {
 		prefix = "• "
 	}
 
-	return strings.TrimSpace(prefix + strings.Join(escapedColumns, " \\| "))
+	// As colunas já chegam escapadas; apenas o separador precisa de escape
+	return strings.TrimSpace(prefix + strings.Join(columns, " "+separator+" "))
 }
 
 func parseTableAlignment(line string) []string {
@@ -14,7 +14,7 @@ import (
 // Constantes para gerenciamento de tamanho
 const (
 	// Margem de segurança para caracteres de escape e formatação
-	safetyMargin = 256
+	safetyMargin = internal.SplitSafetyMargin
 	// Tamanho mínimo para tentar manter em cada parte
 	minPartSize = 512
 )
@@ -25,7 +25,7 @@ func generateMessageID() string {
 	return hex.EncodeToString(b)
 }
 
-func Tokenize(input string) []internal.Block {
+func Tokenize(input string, customBlocks ...types.CustomBlock) []internal.Block {
 	var blocks []internal.Block
 	lines := strings.Split(input, "\n")
 	var buffer []string
@@ -69,9 +69,17 @@ func Tokenize(input string) []internal.Block {
 			continue
 		}
 
+		if blockType, ok := matchCustomBlock(line, customBlocks); ok {
+			if currentBlockType != blockType {
+				flushBuffer()
+				currentBlockType = blockType
+			}
+			buffer = append(buffer, line)
+			continue
+		}
+
 		if utils.TableLinePattern.MatchString(line) {
 			flushBuffer()
-			currentBlockType = internal.BlockTable
 			tableBlock := []string{line}
 			for i+1 < len(lines) && utils.TableLinePattern.MatchString(lines[i+1]) {
 				i++
@@ -105,6 +113,10 @@ func Tokenize(input string) []internal.Block {
 			continue
 		}
 
+		if currentBlockType >= internal.BlockCustom {
+			flushBuffer()
+		}
+
 		if strings.TrimSpace(line) == "" && currentBlockType != internal.BlockText {
 			flushBuffer()
 		}
@@ -116,7 +128,26 @@ func Tokenize(input string) []internal.Block {
 	return blocks
 }
 
+// matchCustomBlock verifica se a linha pertence a algum tipo de bloco
+// registrado pelo usuário
+func matchCustomBlock(line string, customBlocks []types.CustomBlock) (internal.BlockType, bool) {
+	for _, custom := range customBlocks {
+		if custom.Pattern != nil && custom.Pattern.MatchString(line) {
+			return custom.Type, true
+		}
+	}
+	return internal.BlockText, false
+}
+
 func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
+	response, _, err := SplitText(input, maxLength)
+	return response, err
+}
+
+// SplitText divide o texto como BreakLongText e devolve também quantas
+// divisões precisaram ser feitas no meio de um bloco, por ele sozinho ser
+// maior que o limite.
+func SplitText(input string, maxLength int) (types.MessageResponse, int, error) {
 	if maxLength <= 0 {
 		maxLength = internal.TelegramMaxLength
 	}
@@ -135,7 +166,7 @@ func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
 					Content: input,
 				},
 			},
-		}, nil
+		}, 0, nil
 	}
 
 	// Divide o texto em blocos mantendo a ordem
@@ -144,6 +175,7 @@ func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
 	var currentPart strings.Builder
 	currentPartSize := 0
 	partNumber := 1
+	forcedSplits := 0
 
 	// Função para finalizar a parte atual
 	flushCurrentPart := func() {
@@ -174,8 +206,9 @@ func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
 			if block.Type == internal.BlockCode {
 				codeParts, err := divideCodeBlock(blockContent, effectiveMaxLength)
 				if err != nil {
-					return types.MessageResponse{}, err
+					return types.MessageResponse{}, 0, err
 				}
+				forcedSplits += len(codeParts) - 1
 				for _, codePart := range codeParts {
 					parts = append(parts, types.MessagePart{
 						Part:    partNumber,
@@ -186,8 +219,9 @@ func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
 			} else {
 				textParts, err := divideContent(blockContent, effectiveMaxLength)
 				if err != nil {
-					return types.MessageResponse{}, err
+					return types.MessageResponse{}, 0, err
 				}
+				forcedSplits += len(textParts) - 1
 				for _, textPart := range textParts {
 					parts = append(parts, types.MessagePart{
 						Part:    partNumber,
@@ -235,7 +269,7 @@ func BreakLongText(input string, maxLength int) (types.MessageResponse, error) {
 		MessageID:  generateMessageID(),
 		TotalParts: len(parts),
 		Parts:      parts,
-	}, nil
+	}, forcedSplits, nil
 }
 
 // divideContent divide um conteúdo grande em partes menores
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+)
+
+func TestTokenizeBlockAfterTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []internal.BlockType
+	}{
+		{"text after table", "| a | b |\n|---|---|\n| 1 | 2 |\ntexto depois", []internal.BlockType{internal.BlockTable, internal.BlockText}},
+		{"text before and after", "antes\n| a |\ndepois", []internal.BlockType{internal.BlockText, internal.BlockTable, internal.BlockText}},
+		{"blank line after table", "| a |\n\ndepois", []internal.BlockType{internal.BlockTable, internal.BlockText}},
+		{"list after table", "| a |\n- item", []internal.BlockType{internal.BlockTable, internal.BlockList}},
+		{"text between tables", "| a |\nmeio\n| b |", []internal.BlockType{internal.BlockTable, internal.BlockText, internal.BlockTable}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := Tokenize(tt.input)
+			got := make([]internal.BlockType, len(blocks))
+			for i, block := range blocks {
+				got[i] = block.Type
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Tokenize(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Tokenize(%q) = %v, want %v", tt.input, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Package server expõe a conversão por HTTP, para serviços que não são
+// escritos em Go.
+//
+//	POST /convert   converte o corpo (JSON ou text/markdown) e devolve o
+//	                MessageResponse em JSON
+//	GET  /healthz   verificação de saúde
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sshturbo/GoTeleMD"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+const (
+	// DefaultMaxBodySize é o tamanho máximo padrão do corpo das requisições
+	DefaultMaxBodySize = 1 << 20
+	// DefaultShutdownTimeout é o tempo dado às requisições em andamento ao
+	// encerrar o servidor
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// Chaves de configuração que controlam recursos do servidor ou a política de
+// segurança do operador e por isso não podem ser alteradas por requisição.
+// O payload fica de fora: ele só descreve a resposta devolvida a quem pediu.
+var serverOnlyKeys = map[string]bool{
+	"num_workers":          true,
+	"worker_queue_size":    true,
+	"max_concurrent_parts": true,
+	"enable_debug_logs":    true,
+	"link_policy":          true,
+}
+
+type Server struct {
+	config          *types.Config
+	maxBodySize     int64
+	shutdownTimeout time.Duration
+	logger          *slog.Logger
+	mux             *http.ServeMux
+}
+
+type Option func(*Server)
+
+// WithMaxBodySize limita o tamanho do corpo das requisições, em bytes
+func WithMaxBodySize(size int64) Option {
+	return func(s *Server) {
+		if size > 0 {
+			s.maxBodySize = size
+		}
+	}
+}
+
+// WithShutdownTimeout define quanto tempo ListenAndServe espera as requisições
+// em andamento ao encerrar
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		if timeout > 0 {
+			s.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithLogger define o logger das requisições. Por padrão usa o logger da
+// configuração.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// New cria o servidor. config é a configuração base de todas as conversões;
+// cada requisição pode sobrescrever parte dela. Com nil, usa a configuração
+// padrão.
+func New(config *types.Config, options ...Option) *Server {
+	if config == nil {
+		config = types.DefaultConfig()
+	}
+	s := &Server{
+		config:          config,
+		maxBodySize:     DefaultMaxBodySize,
+		shutdownTimeout: DefaultShutdownTimeout,
+		logger:          config.Log(),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /convert", s.handleConvert)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe atende em addr até ctx ser cancelado, e então encerra o
+// servidor esperando as requisições em andamento
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		s.logger.Info("server listening", slog.String("addr", addr))
+		errChan <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// convertRequest é o corpo JSON de POST /convert. Options usa as mesmas chaves
+// dos arquivos de configuração.
+type convertRequest struct {
+	Markdown string          `json:"markdown"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+
+	// As opções da query valem para qualquer corpo; as do JSON vêm depois
+	config := s.config.Clone()
+	if err := applyQuery(config, r); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	markdown, err := s.readMarkdown(r, config)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			s.writeError(w, http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+		case errors.Is(err, errUnsupportedMediaType):
+			s.writeError(w, http.StatusUnsupportedMediaType, err)
+		default:
+			s.writeError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	converter, err := GoTeleMD.NewConverterFromConfig(config)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	response, err := converter.Convert(markdown)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, types.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		s.writeError(w, status, err)
+		return
+	}
+
+	s.logger.Debug("message converted",
+		slog.String("message_id", response.MessageID),
+		slog.Int("input_size", len(markdown)),
+		slog.Int("parts", response.TotalParts))
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+var errUnsupportedMediaType = errors.New("unsupported content type; use application/json, text/markdown or text/plain")
+
+func (s *Server) readMarkdown(r *http.Request, config *types.Config) (string, error) {
+	mediaType := "text/plain"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return "", errUnsupportedMediaType
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		var req convertRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			return "", fmt.Errorf("invalid JSON body: %w", err)
+		}
+		if len(req.Options) > 0 {
+			if err := applyOptions(config, req.Options); err != nil {
+				return "", err
+			}
+		}
+		return req.Markdown, nil
+	case "text/markdown", "text/plain":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+	default:
+		return "", errUnsupportedMediaType
+	}
+}
+
+// applyQuery aplica as opções da query string, com as chaves dos arquivos de
+// configuração (?safety_level=2&output_mode=plain)
+func applyQuery(config *types.Config, r *http.Request) error {
+	query := r.URL.Query()
+	if len(query) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(query))
+	for key, list := range query {
+		if err := checkRequestKey(key); err != nil {
+			return err
+		}
+		values[key] = strings.Join(list, ",")
+	}
+	return config.LoadMap(values)
+}
+
+func applyOptions(config *types.Config, raw json.RawMessage) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return fmt.Errorf("options must be a JSON object: %w", err)
+	}
+	for key := range keys {
+		if err := checkRequestKey(key); err != nil {
+			return err
+		}
+	}
+	return config.LoadJSON(raw)
+}
+
+func checkRequestKey(key string) error {
+	root, _, _ := strings.Cut(key, ".")
+	if serverOnlyKeys[root] {
+		return fmt.Errorf("option %q can only be set in the server configuration", key)
+	}
+	return nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		s.logger.Error("conversion request failed", slog.Int("status", status), slog.Any("error", err))
+	}
+	s.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	// O conteúdo das mensagens tem >, < e & com frequência; mantê-los legíveis
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		s.logger.Error("failed to write response", slog.Any("error", err))
+	}
+}
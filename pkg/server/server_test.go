@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestConvertStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "markdown body",
+			target:      "/convert",
+			contentType: "text/markdown; charset=utf-8",
+			body:        "# Título",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "json body",
+			target:      "/convert",
+			contentType: "application/json",
+			body:        `{"markdown": "**oi**", "options": {"safety_level": 2}}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "body too large",
+			target:      "/convert",
+			contentType: "text/plain",
+			body:        strings.Repeat("a", 129),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantError:   "request body exceeds 128 bytes",
+		},
+		{
+			name:        "unsupported content type",
+			target:      "/convert",
+			contentType: "application/xml",
+			body:        "<p>oi</p>",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantError:   "unsupported content type",
+		},
+		{
+			name:        "malformed content type",
+			target:      "/convert",
+			contentType: "text/",
+			body:        "oi",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "invalid json",
+			target:      "/convert",
+			contentType: "application/json",
+			body:        `{"markdown": `,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid JSON body",
+		},
+		{
+			name:        "unknown json field",
+			target:      "/convert",
+			contentType: "application/json",
+			body:        `{"markdown": "oi", "extra": true}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "empty input",
+			target:      "/convert",
+			contentType: "text/plain",
+			body:        "",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "invalid option value",
+			target:      "/convert?safety_level=7",
+			contentType: "text/plain",
+			body:        "oi",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "server-only query option",
+			target:      "/convert?num_workers=64",
+			contentType: "text/plain",
+			body:        "oi",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   `option "num_workers" can only be set in the server configuration`,
+		},
+		{
+			name:        "link policy query option",
+			target:      "/convert?link_policy.allowed_schemes=javascript",
+			contentType: "text/plain",
+			body:        "[x](javascript:alert(1))",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   `option "link_policy.allowed_schemes" can only be set in the server configuration`,
+		},
+		{
+			name:        "link policy json option",
+			target:      "/convert",
+			contentType: "application/json",
+			body:        `{"markdown": "oi", "options": {"link_policy": {"allowed_schemes": ["javascript"]}}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   `option "link_policy" can only be set in the server configuration`,
+		},
+	}
+
+	s := New(nil, WithMaxBodySize(128))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var response types.MessageResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.TotalParts == 0 {
+					t.Errorf("body %s is not a MessageResponse (%v)", rec.Body, err)
+				}
+				return
+			}
+			var errResp errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &errResp); err != nil || errResp.Error == "" {
+				t.Fatalf("body %s is not an error response (%v)", rec.Body, err)
+			}
+			if !strings.Contains(errResp.Error, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", errResp.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestConvertKeepsBaseConfig(t *testing.T) {
+	config := types.DefaultConfig()
+	s := New(config)
+
+	req := httptest.NewRequest(http.MethodPost, "/convert?safety_level=2", strings.NewReader("**oi**"))
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if config.SafetyLevel != types.DefaultConfig().SafetyLevel {
+		t.Errorf("request option changed the server configuration (safety_level = %d)", config.SafetyLevel)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("GET /healthz = %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIError é um erro devolvido pela Bot API ({"ok": false, ...})
+type APIError struct {
+	Method      string
+	ErrorCode   int
+	Description string
+	// RetryAfter é o tempo pedido pela API antes de tentar de novo, nas
+	// respostas 429
+	RetryAfter time.Duration
+	// MigrateToChatID é o novo ID do chat quando um grupo virou supergrupo
+	MigrateToChatID int64
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %s: %s (%d)", e.Method, e.Description, e.ErrorCode)
+}
+
+// apiResponse é o envelope de todas as respostas da Bot API
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter      int   `json:"retry_after"`
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+// call envia payload como JSON para o método da Bot API e decodifica o
+// resultado em result
+func (s *Sender) call(ctx context.Context, method string, payload, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("telegram: %s: encoding request: %w", method, err)
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", s.baseURL, s.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telegram: %s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// A URL do erro contém o token do bot
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("telegram: %s: reading response: %w", method, err)
+	}
+	var apiResp apiResponse
+	if err := json.Unmarshal(data, &apiResp); err != nil {
+		return fmt.Errorf("telegram: %s: unexpected response (HTTP %d): %w", method, resp.StatusCode, err)
+	}
+	if !apiResp.OK {
+		apiErr := &APIError{
+			Method:      method,
+			ErrorCode:   apiResp.ErrorCode,
+			Description: apiResp.Description,
+		}
+		if apiResp.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(apiResp.Parameters.RetryAfter) * time.Second
+			apiErr.MigrateToChatID = apiResp.Parameters.MigrateToChatID
+		}
+		return apiErr
+	}
+	if result != nil {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("telegram: %s: decoding result: %w", method, err)
+		}
+	}
+	return nil
+}
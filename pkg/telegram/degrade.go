@@ -0,0 +1,303 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sshturbo/GoTeleMD"
+	"github.com/sshturbo/GoTeleMD/internal"
+	"github.com/sshturbo/GoTeleMD/pkg/formatter"
+	"github.com/sshturbo/GoTeleMD/pkg/parser"
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// pendingPart é uma parte a enviar junto com o trecho do Markdown que a gerou,
+// para que possa ser convertida de novo
+type pendingPart struct {
+	part   types.MessagePart
+	source string
+}
+
+// SendMarkdown converte o Markdown com a configuração do Sender (ver
+// WithConfig) e envia as partes ao chat, na ordem, devolvendo os IDs das
+// mensagens enviadas.
+//
+// Quando a Bot API recusa uma parte com "can't parse entities", o trecho que
+// a gerou é convertido de novo com o próximo nível de segurança (BASIC, depois
+// STRICT) e, por fim, como texto simples sem parse_mode, e reenviado.
+//
+// Para isso cada trecho do Markdown é convertido separadamente e dividido de
+// novo até gerar uma parte só, de modo que reenviar uma parte recusada nunca
+// repete as partes já entregues.
+func (s *Sender) SendMarkdown(ctx context.Context, chatID int64, markdown string) ([]int, error) {
+	messageID, parts, err := s.prepare(markdown)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(parts))
+	for _, pending := range parts {
+		id, err := s.sendPart(ctx, chatID, messageID, pending.part, s.replyTo(ids))
+		if err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		if _, ok := entityError(err); ok {
+			ids, err = s.sendDegraded(ctx, chatID, messageID, pending, degradeSteps(s.config), len(parts), ids, err)
+		}
+		if err != nil {
+			return ids, s.partFailed(chatID, messageID, pending.part, len(parts), err)
+		}
+	}
+	return ids, nil
+}
+
+// prepare aplica os middlewares de entrada, divide o Markdown em trechos e
+// converte cada um. O teclado, os middlewares de saída, a escolha da
+// pré-visualização e os payloads são aplicados no fim, considerando todas as
+// partes.
+func (s *Sender) prepare(markdown string) (string, []pendingPart, error) {
+	if strings.TrimSpace(markdown) == "" {
+		return "", nil, types.NewError(types.ErrInvalidInput, "input cannot be empty", nil)
+	}
+
+	// Como em Converter.Convert, os middlewares de entrada veem o documento
+	// inteiro, uma única vez
+	for _, middleware := range s.config.InputMiddleware {
+		markdown = middleware(markdown)
+	}
+	markdown = strings.TrimSpace(markdown)
+	if markdown == "" {
+		return "", nil, types.NewError(types.ErrInvalidInput, "input is empty after input middleware", nil)
+	}
+
+	// Os botões saem antes da divisão, para irem sempre na última parte
+	var keyboard *types.InlineKeyboardMarkup
+	if s.config.ExtractButtons {
+		markdown, keyboard = formatter.ExtractButtons(markdown, s.config, nil)
+		if strings.TrimSpace(markdown) == "" {
+			return "", nil, types.NewError(types.ErrInvalidInput, "input has no text besides buttons", nil)
+		}
+	}
+
+	chunks, _, err := parser.SplitText(markdown, chunkLength(s.config))
+	if err != nil {
+		return "", nil, types.NewError(types.ErrProcessingFailed, "failed to break text", err)
+	}
+
+	var parts []pendingPart
+	for _, chunk := range chunks.Parts {
+		units, err := convertUnits(s.config, chunk.Content)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, units...)
+	}
+	parts[len(parts)-1].part.ReplyMarkup = keyboard
+	for i := range parts {
+		parts[i].part.Part = i + 1
+		parts[i].part = applyOutputMiddleware(s.config, parts[i].part, len(parts))
+	}
+	converted := make([]types.MessagePart, len(parts))
+	for i := range parts {
+		converted[i] = parts[i].part
+	}
+	types.ApplyLinkPreview(converted, s.config)
+	if s.config.Payload != nil {
+		types.BuildPayloads(converted, s.config.Payload)
+	}
+	for i := range parts {
+		parts[i].part = converted[i]
+	}
+	return chunks.MessageID, parts, nil
+}
+
+// convertUnits converte o trecho e, se ele gerar mais de uma parte, o divide
+// em trechos menores até cada um gerar uma só, para que cada parte tenha o
+// seu próprio trecho
+func convertUnits(config *types.Config, source string) ([]pendingPart, error) {
+	response, err := convertWithoutMiddleware(config, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Parts) > 1 {
+		pieces, _, err := parser.SplitText(source, internal.SplitSafetyMargin+utf8.RuneCountInString(source)/2)
+		if err != nil {
+			return nil, types.NewError(types.ErrProcessingFailed, "failed to break text", err)
+		}
+		// Um trecho que não se divide mais fica com todas as partes
+		if len(pieces.Parts) > 1 {
+			var units []pendingPart
+			for _, piece := range pieces.Parts {
+				more, err := convertUnits(config, piece.Content)
+				if err != nil {
+					return nil, err
+				}
+				units = append(units, more...)
+			}
+			return units, nil
+		}
+	}
+
+	units := make([]pendingPart, len(response.Parts))
+	for i, part := range response.Parts {
+		units[i] = pendingPart{part: part, source: source}
+	}
+	return units, nil
+}
+
+// sendDegraded reconverte o trecho de uma parte recusada com o primeiro dos
+// passos e envia as partes resultantes. Se uma delas também for recusada, só
+// ela é reconvertida com os passos seguintes; as já entregues não se repetem.
+func (s *Sender) sendDegraded(ctx context.Context, chatID int64, messageID string, pending pendingPart, steps []*types.Config, total int, ids []int, cause error) ([]int, error) {
+	if len(steps) == 0 {
+		return ids, cause
+	}
+	config := steps[0]
+	offset, _ := entityError(cause)
+	s.logger.Warn("telegram rejected message entities",
+		slog.String("message_id", messageID),
+		slog.Int("part", pending.part.Part),
+		slog.Int("byte_offset", offset),
+		slog.Any("error", cause))
+
+	units, err := convertUnits(config, pending.source)
+	if err != nil {
+		return ids, err
+	}
+	for i, unit := range units {
+		first, last := i == 0, i == len(units)-1
+		part := unit.part
+		part.Part = pending.part.Part
+		part = applyOutputMiddleware(config, part, total)
+		part.LinkPreview = degradedPreview(pending.part.LinkPreview, first)
+		if last {
+			part.ReplyMarkup = pending.part.ReplyMarkup
+		}
+		part.Payload = degradedPayload(pending.part.Payload, first, last)
+		unit.part = part
+
+		id, err := s.sendPart(ctx, chatID, messageID, part, s.replyTo(ids))
+		if err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		if _, ok := entityError(err); !ok {
+			return ids, err
+		}
+		if ids, err = s.sendDegraded(ctx, chatID, messageID, unit, steps[1:], total, ids, err); err != nil {
+			return ids, err
+		}
+	}
+	s.logger.Info("message part sent with degraded formatting",
+		slog.String("message_id", messageID),
+		slog.Int("part", pending.part.Part),
+		slog.Int("safety_level", config.SafetyLevel),
+		slog.String("parse_mode", units[0].part.ParseMode))
+	return ids, nil
+}
+
+// degradeSteps devolve as configurações usadas, em ordem, para reenviar uma
+// parte recusada: cada nível de segurança acima do configurado e, por fim, o
+// texto simples
+func degradeSteps(config *types.Config) []*types.Config {
+	if config.OutputMode == types.OutputPlainText {
+		return nil
+	}
+	var steps []*types.Config
+	for level := config.SafetyLevel + 1; level <= internal.SAFETYLEVELSTRICT; level++ {
+		step := config.Clone()
+		step.SafetyLevel = level
+		// Os níveis por tipo de bloco também não podem ficar abaixo do novo
+		for blockType, blockLevel := range step.BlockSafetyLevels {
+			step.BlockSafetyLevels[blockType] = max(blockLevel, level)
+		}
+		steps = append(steps, step)
+	}
+	plain := config.Clone()
+	plain.OutputMode = types.OutputPlainText
+	return append(steps, plain)
+}
+
+// chunkLength é o tamanho dos trechos do Markdown convertidos separadamente.
+// A margem extra dá espaço para os caracteres de escape, para que cada trecho
+// caiba em uma mensagem.
+func chunkLength(config *types.Config) int {
+	length := config.MaxMessageLength
+	if length <= 0 {
+		length = internal.TelegramMaxLength
+	}
+	return max(length-internal.SplitSafetyMargin, internal.MinMessageLength)
+}
+
+// degradedPreview mantém a pré-visualização escolhida só na primeira das
+// partes que substituem a recusada
+func degradedPreview(original *types.LinkPreviewOptions, first bool) *types.LinkPreviewOptions {
+	if original == nil || first || original.IsDisabled {
+		return original
+	}
+	return &types.LinkPreviewOptions{IsDisabled: true}
+}
+
+// degradedPayload adapta o payload da parte recusada para uma das partes que a
+// substituem: só a primeira responde a outra mensagem e só a última leva o
+// teclado
+func degradedPayload(original *types.SendMessagePayload, first, last bool) *types.SendMessagePayload {
+	if original == nil {
+		return nil
+	}
+	payload := *original
+	if !first {
+		payload.ReplyParameters = nil
+		payload.LinkPreviewOptions = degradedPreview(original.LinkPreviewOptions, false)
+	}
+	if !last {
+		payload.ReplyMarkup = nil
+	}
+	return &payload
+}
+
+func convertWithoutMiddleware(config *types.Config, markdown string) (types.MessageResponse, error) {
+	config = config.Clone()
+	config.InputMiddleware = nil
+	config.OutputMiddleware = nil
+	config.LinkPreview = types.LinkPreviewDefault
+	config.ExtractButtons = false
+	config.Payload = nil
+	converter, err := GoTeleMD.NewConverterFromConfig(config)
+	if err != nil {
+		return types.MessageResponse{}, err
+	}
+	return converter.Convert(markdown)
+}
+
+func applyOutputMiddleware(config *types.Config, part types.MessagePart, total int) types.MessagePart {
+	for _, middleware := range config.OutputMiddleware {
+		part = middleware(part, total)
+	}
+	return part
+}
+
+var entityOffsetPattern = regexp.MustCompile(`byte offset (\d+)`)
+
+// entityError informa se err é a recusa da formatação pela Bot API ("Bad
+// Request: can't parse entities") e, quando a descrição traz, a posição em
+// bytes do problema na parte enviada (ou -1)
+func entityError(err error) (int, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != 400 ||
+		!strings.Contains(strings.ToLower(apiErr.Description), "can't parse entities") {
+		return -1, false
+	}
+	if match := entityOffsetPattern.FindStringSubmatch(apiErr.Description); match != nil {
+		if offset, err := strconv.Atoi(match[1]); err == nil {
+			return offset, true
+		}
+	}
+	return -1, true
+}
@@ -0,0 +1,213 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+const entityErrorReply = `{"ok": false, "error_code": 400, "description": "Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 4"}`
+
+func TestSendMarkdownDegradesOnEntityError(t *testing.T) {
+	tests := []struct {
+		name string
+		// rejected é quantos envios com parse_mode o Telegram recusa
+		rejected  int
+		wantModes []string
+		wantText  string
+	}{
+		{name: "accepted", rejected: 0, wantModes: []string{"MarkdownV2"},
+			wantText: `Olá *mundo* e \(parênteses\)`},
+		{name: "strict", rejected: 1, wantModes: []string{"MarkdownV2", "MarkdownV2"},
+			wantText: `Olá \*\*mundo\*\* e \(parênteses\)`},
+		{name: "plain text", rejected: 2, wantModes: []string{"MarkdownV2", "MarkdownV2", ""},
+			wantText: "Olá mundo e (parênteses)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, server := newFakeAPI(t, func(n int, req types.SendMessagePayload) string {
+				if req.ParseMode != "" && n < tt.rejected {
+					return entityErrorReply
+				}
+				return okReply(100 + n)
+			})
+			sender := New(testToken, WithBaseURL(server.URL))
+
+			ids, err := sender.SendMarkdown(context.Background(), 42, "Olá **mundo** e (parênteses)")
+			if err != nil {
+				t.Fatalf("SendMarkdown: %v", err)
+			}
+			if len(ids) != 1 {
+				t.Errorf("ids = %v, want one message", ids)
+			}
+
+			requests := api.sent()
+			var modes []string
+			for _, req := range requests {
+				modes = append(modes, req.ParseMode)
+			}
+			if fmt.Sprint(modes) != fmt.Sprint(tt.wantModes) {
+				t.Fatalf("parse modes = %q, want %q", modes, tt.wantModes)
+			}
+
+			if last := requests[len(requests)-1].Text; last != tt.wantText {
+				t.Errorf("sent text = %q, want %q", last, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSendMarkdownDoesNotDegradeOtherErrors(t *testing.T) {
+	api, server := newFakeAPI(t, func(int, types.SendMessagePayload) string {
+		return `{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`
+	})
+	sender := New(testToken, WithBaseURL(server.URL))
+
+	_, err := sender.SendMarkdown(context.Background(), 42, "Olá **mundo**")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Description != "Bad Request: chat not found" {
+		t.Fatalf("error = %v, want the API error", err)
+	}
+	if len(api.sent()) != 1 {
+		t.Errorf("got %d requests, want no resend", len(api.sent()))
+	}
+}
+
+func TestSendMarkdownFailsWhenEveryStepIsRejected(t *testing.T) {
+	api, server := newFakeAPI(t, func(int, types.SendMessagePayload) string {
+		return entityErrorReply
+	})
+	sender := New(testToken, WithBaseURL(server.URL))
+
+	_, err := sender.SendMarkdown(context.Background(), 42, "Olá **mundo**")
+	if _, ok := entityError(err); !ok {
+		t.Fatalf("error = %v, want the last entity error", err)
+	}
+	// BASIC (configurado), STRICT e texto simples
+	if len(api.sent()) != 3 {
+		t.Errorf("got %d requests, want 3", len(api.sent()))
+	}
+}
+
+func TestEntityError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantOffset int
+		wantOK     bool
+	}{
+		{&APIError{ErrorCode: 400, Description: "Bad Request: can't parse entities: Character '.' is reserved at byte offset 12"}, 12, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: Can't parse entities: unsupported start tag"}, -1, true},
+		{fmt.Errorf("sending part 1 of 1: %w", &APIError{ErrorCode: 400, Description: "Bad Request: can't parse entities"}), -1, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: chat not found"}, -1, false},
+		{errors.New("can't parse entities"), -1, false},
+	}
+	for _, tt := range tests {
+		offset, ok := entityError(tt.err)
+		if offset != tt.wantOffset || ok != tt.wantOK {
+			t.Errorf("entityError(%v) = %d, %v; want %d, %v", tt.err, offset, ok, tt.wantOffset, tt.wantOK)
+		}
+	}
+}
+
+func TestSendMarkdownAppliesInputMiddlewareOnce(t *testing.T) {
+	_, server := newFakeAPI(t, func(n int, req types.SendMessagePayload) string {
+		if n == 0 {
+			return entityErrorReply
+		}
+		return okReply(100 + n)
+	})
+	calls := 0
+	config := types.DefaultConfig()
+	config.InputMiddleware = []types.InputMiddleware{func(input string) string {
+		calls++
+		return input + "!"
+	}}
+	sender := New(testToken, WithBaseURL(server.URL), WithConfig(config))
+
+	if _, err := sender.SendMarkdown(context.Background(), 42, "Olá **mundo**"); err != nil {
+		t.Fatalf("SendMarkdown: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("input middleware ran %d times, want once", calls)
+	}
+}
+
+func TestSendMarkdownDoesNotResendDeliveredParts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// reject diz quais envios o Telegram recusa
+		reject    func(n int) bool
+		wantModes []string
+		// wantLetters são as letras do texto entregue, sem escapes e marcas
+		wantLetters string
+	}{
+		{
+			// Um trecho só que, com o escape dos pontos, vira duas partes
+			name:        "second part of a chunk rejected",
+			input:       "primeira " + strings.Repeat(".", 100) + "\n\nsegunda " + strings.Repeat(".", 100),
+			reject:      func(n int) bool { return n == 1 },
+			wantModes:   []string{"MarkdownV2", "MarkdownV2", "MarkdownV2"},
+			wantLetters: "primeirasegunda",
+		},
+		{
+			// No nível STRICT os asteriscos escapados fazem o trecho virar
+			// várias partes, e a segunda delas também é recusada
+			name:        "degraded part rejected again",
+			input:       strings.TrimSpace(strings.Repeat("*a* ", 64)),
+			reject:      func(n int) bool { return n == 0 || n == 2 },
+			wantModes:   []string{"MarkdownV2", "MarkdownV2", "MarkdownV2", "", "MarkdownV2"},
+			wantLetters: strings.Repeat("a", 64),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+				if tt.reject(n) {
+					return entityErrorReply
+				}
+				return okReply(100 + n)
+			})
+			config := types.DefaultConfig()
+			config.MaxMessageLength = 600
+			sender := New(testToken, WithBaseURL(server.URL), WithConfig(config))
+
+			ids, err := sender.SendMarkdown(context.Background(), 42, tt.input)
+			if err != nil {
+				t.Fatalf("SendMarkdown: %v", err)
+			}
+
+			var modes []string
+			var letters strings.Builder
+			delivered := 0
+			for n, req := range api.sent() {
+				modes = append(modes, req.ParseMode)
+				if tt.reject(n) {
+					continue
+				}
+				delivered++
+				for _, r := range req.Text {
+					if unicode.IsLetter(r) {
+						letters.WriteRune(r)
+					}
+				}
+			}
+			if fmt.Sprint(modes) != fmt.Sprint(tt.wantModes) {
+				t.Errorf("parse modes = %q, want %q", modes, tt.wantModes)
+			}
+			if letters.String() != tt.wantLetters {
+				t.Errorf("delivered letters = %q, want %q", letters.String(), tt.wantLetters)
+			}
+			if len(ids) != delivered {
+				t.Errorf("ids = %v, want %d messages", ids, delivered)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter espaça os envios em pelo menos interval. Uma resposta 429 adia o
+// próximo envio com pause.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(perSecond float64) *limiter {
+	l := &limiter{}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// wait reserva o próximo horário livre e espera até ele
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause impede novos envios pelos próximos d
+func (l *limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// idle informa se o limitador não tem envios reservados, podendo ser
+// descartado
+func (l *limiter) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next.Before(now)
+}
+
+// chatLimiters guarda um limiter por chat, descartando os parados para que o
+// mapa não cresça sem limite
+type chatLimiters struct {
+	mu        sync.Mutex
+	perSecond float64
+	chats     map[int64]*limiter
+}
+
+// Acima desse número de chats, os limitadores parados são descartados
+const maxIdleChatLimiters = 1024
+
+func (c *chatLimiters) get(chatID int64) *limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.chats[chatID]; ok {
+		return l
+	}
+	if len(c.chats) >= maxIdleChatLimiters {
+		now := time.Now()
+		for id, l := range c.chats {
+			if l.idle(now) {
+				delete(c.chats, id)
+			}
+		}
+	}
+	l := newLimiter(c.perSecond)
+	c.chats[chatID] = l
+	return l
+}
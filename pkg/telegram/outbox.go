@@ -0,0 +1,207 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// OutboxEntry é uma mensagem guardada no outbox até todas as partes serem
+// enviadas
+type OutboxEntry struct {
+	// Sequence ordena as mensagens na ordem em que foram enfileiradas
+	Sequence  uint64              `json:"sequence"`
+	ChatID    int64               `json:"chat_id"`
+	MessageID string              `json:"message_id"`
+	Parts     []types.MessagePart `json:"parts"`
+	// SentIDs são os IDs das partes já entregues, na ordem das partes
+	SentIDs []int `json:"sent_ids,omitempty"`
+}
+
+// Outbox guarda as mensagens da Queue enquanto são enviadas, para que as
+// partes que faltam sejam retomadas depois de um reinício (ver Queue.Resume).
+// Uma mensagem é identificada pelo chat e pelo MessageID.
+//
+// A entrega é at-least-once: uma parte é registrada com MarkSent logo depois
+// da resposta do Telegram, e um reinício entre a resposta e o registro faz
+// essa parte ser enviada de novo.
+type Outbox interface {
+	// Save guarda uma mensagem nova e define a sua sequência
+	Save(entry *OutboxEntry) error
+	// MarkSent registra que a próxima parte pendente foi entregue
+	MarkSent(chatID int64, messageID string, sentID int) error
+	// Remove descarta a mensagem
+	Remove(chatID int64, messageID string) error
+	// Pending devolve as mensagens guardadas, na ordem da sequência
+	Pending() ([]OutboxEntry, error)
+}
+
+// FileOutbox é um Outbox em disco, com um arquivo JSON por mensagem. Cada
+// alteração é gravada em um arquivo temporário e renomeada, para que um
+// reinício no meio da escrita não corrompa a mensagem.
+type FileOutbox struct {
+	mu       sync.Mutex
+	dir      string
+	sequence uint64
+	entries  map[outboxKey]*OutboxEntry
+}
+
+type outboxKey struct {
+	chatID    int64
+	messageID string
+}
+
+// NewFileOutbox abre o outbox no diretório, criando-o se não existir, e
+// carrega as mensagens pendentes
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("telegram: outbox: %w", err)
+	}
+	o := &FileOutbox{dir: dir, entries: make(map[outboxKey]*OutboxEntry)}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("telegram: outbox: %w", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("telegram: outbox: %w", err)
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("telegram: outbox: %s: %w", filepath.Base(file), err)
+		}
+		if filepath.Base(file) != entry.fileName() {
+			return nil, fmt.Errorf("telegram: outbox: %s: file does not match its entry", filepath.Base(file))
+		}
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("telegram: outbox: %s: %w", filepath.Base(file), err)
+		}
+		o.entries[entry.key()] = &entry
+		o.sequence = max(o.sequence, entry.Sequence)
+	}
+	return o, nil
+}
+
+func (o *FileOutbox) Save(entry *OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, exists := o.entries[entry.key()]; exists {
+		return fmt.Errorf("telegram: outbox: message %s for chat %d is already pending", entry.MessageID, entry.ChatID)
+	}
+	o.sequence++
+	entry.Sequence = o.sequence
+	saved := *entry
+	if err := o.write(&saved); err != nil {
+		return err
+	}
+	o.entries[saved.key()] = &saved
+	return nil
+}
+
+func (o *FileOutbox) MarkSent(chatID int64, messageID string, sentID int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entry, ok := o.entries[outboxKey{chatID, messageID}]
+	if !ok {
+		return fmt.Errorf("telegram: outbox: message %s for chat %d is not pending", messageID, chatID)
+	}
+	if len(entry.SentIDs) >= len(entry.Parts) {
+		return fmt.Errorf("telegram: outbox: message %s for chat %d has no pending parts", messageID, chatID)
+	}
+	updated := *entry
+	updated.SentIDs = append(append([]int(nil), entry.SentIDs...), sentID)
+	if err := o.write(&updated); err != nil {
+		return err
+	}
+	o.entries[updated.key()] = &updated
+	return nil
+}
+
+func (o *FileOutbox) Remove(chatID int64, messageID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := outboxKey{chatID, messageID}
+	entry, ok := o.entries[key]
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(o.dir, entry.fileName())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	delete(o.entries, key)
+	return nil
+}
+
+func (o *FileOutbox) Pending() ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]OutboxEntry, 0, len(o.entries))
+	for _, entry := range o.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	return entries, nil
+}
+
+// write grava a mensagem de forma atômica: arquivo temporário, fsync e rename
+func (o *FileOutbox) write(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	tmp, err := os.CreateTemp(o.dir, ".pending-*")
+	if err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(o.dir, entry.fileName())); err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	return syncDir(o.dir)
+}
+
+// validate confere se a mensagem pode ser retomada: não pode haver mais IDs
+// entregues do que partes
+func (e *OutboxEntry) validate() error {
+	if len(e.SentIDs) > len(e.Parts) {
+		return fmt.Errorf("message %s for chat %d has %d sent parts but only %d parts",
+			e.MessageID, e.ChatID, len(e.SentIDs), len(e.Parts))
+	}
+	return nil
+}
+
+func (e *OutboxEntry) key() outboxKey {
+	return outboxKey{e.ChatID, e.MessageID}
+}
+
+// fileName monta o nome do arquivo da mensagem. O MessageID pode vir de fora,
+// então só caracteres seguros entram no nome.
+func (e *OutboxEntry) fileName() string {
+	safeID := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, e.MessageID)
+	return fmt.Sprintf("%020d_%d_%s.json", e.Sequence, e.ChatID, safeID)
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package telegram
+
+import (
+	"fmt"
+	"os"
+)
+
+// syncDir garante que o rename chegou ao disco
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("telegram: outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package telegram
+
+// syncDir não faz nada no Windows, onde não é possível dar fsync em um
+// diretório. O NTFS registra o rename no seu journal.
+func syncDir(dir string) error {
+	return nil
+}
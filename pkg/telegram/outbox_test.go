@@ -0,0 +1,207 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+func TestFileOutboxRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutbox: %v", err)
+	}
+
+	entry := &OutboxEntry{ChatID: 42, MessageID: "msg/1", Parts: testResponse("um", "dois", "três").Parts}
+	if err := outbox.Save(entry); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := outbox.MarkSent(42, "msg/1", 100); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+
+	reopened, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	pending, err := reopened.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("Pending = %+v, want one entry", pending)
+	}
+	got := pending[0]
+	if got.Sequence != 1 || got.ChatID != 42 || got.MessageID != "msg/1" || len(got.Parts) != 3 ||
+		fmt.Sprint(got.SentIDs) != "[100]" || got.Parts[2].Content != "três" {
+		t.Errorf("reloaded entry = %+v", got)
+	}
+
+	// A sequência continua de onde parou
+	next := &OutboxEntry{ChatID: 7, MessageID: "msg-2", Parts: testResponse("x").Parts}
+	if err := reopened.Save(next); err != nil {
+		t.Fatalf("Save after reopening: %v", err)
+	}
+	if next.Sequence != 2 {
+		t.Errorf("Sequence = %d, want 2", next.Sequence)
+	}
+
+	if err := reopened.Save(&OutboxEntry{ChatID: 42, MessageID: "msg/1"}); err == nil {
+		t.Error("Save accepted a message that is already pending")
+	}
+	if err := reopened.Remove(42, "msg/1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := reopened.MarkSent(42, "msg/1", 101); err == nil {
+		t.Error("MarkSent accepted a removed message")
+	}
+
+	final, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	pending, _ = final.Pending()
+	if len(pending) != 1 || pending[0].MessageID != "msg-2" {
+		t.Errorf("Pending after Remove = %+v, want only msg-2", pending)
+	}
+}
+
+func TestFileOutboxWritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	// Um arquivo temporário deixado por uma escrita interrompida é ignorado
+	if err := os.WriteFile(filepath.Join(dir, ".pending-123"), []byte(`{"sequence":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutbox with a stale temporary file: %v", err)
+	}
+
+	entry := &OutboxEntry{ChatID: 42, MessageID: "m", Parts: testResponse("um", "dois").Parts}
+	if err := outbox.Save(entry); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := outbox.MarkSent(42, "m", 100); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryFiles, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(entryFiles) != 1 || filepath.Base(entryFiles[0]) != entry.fileName() {
+		t.Errorf("entry files = %v, want only %s", entryFiles, entry.fileName())
+	}
+	for _, file := range files {
+		if base := filepath.Base(file); base != entry.fileName() && base != ".pending-123" {
+			t.Errorf("temporary file %s was left behind", base)
+		}
+	}
+}
+
+func TestFileOutboxRejectsCorruptEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"truncated", `{"sequence":`},
+		{"more sent ids than parts", `{"sequence":1,"chat_id":42,"message_id":"m","parts":[{"part":1,"content":"um"}],"sent_ids":[100,101]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "00000000000000000001_42_m.json"), []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewFileOutbox(dir); err == nil {
+				t.Error("NewFileOutbox accepted a corrupt entry")
+			}
+		})
+	}
+}
+
+// staticOutbox devolve sempre as mesmas mensagens pendentes, como um Outbox
+// de outro armazenamento que não confere as mensagens
+type staticOutbox struct {
+	*FileOutbox
+	entries []OutboxEntry
+}
+
+func (o *staticOutbox) Pending() ([]OutboxEntry, error) {
+	return o.entries, nil
+}
+
+func TestQueueResumeRejectsMoreSentIDsThanParts(t *testing.T) {
+	files, err := NewFileOutbox(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileOutbox: %v", err)
+	}
+	outbox := &staticOutbox{FileOutbox: files, entries: []OutboxEntry{
+		{Sequence: 1, ChatID: 42, MessageID: "m", Parts: testResponse("um").Parts, SentIDs: []int{100, 101}},
+	}}
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		return okReply(200 + n)
+	})
+	q := newTestQueue(t, New(testToken, WithBaseURL(server.URL)), WithOutbox(outbox))
+
+	results, err := q.Resume(context.Background())
+	if err == nil {
+		t.Fatal("Resume accepted an entry with more sent ids than parts")
+	}
+	if len(results) != 0 || len(api.sent()) != 0 {
+		t.Errorf("Resume enqueued the entry: %d results, %d requests", len(results), len(api.sent()))
+	}
+}
+
+func TestQueueResumeSkipsDeliveredParts(t *testing.T) {
+	outbox, err := NewFileOutbox(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileOutbox: %v", err)
+	}
+	entry := &OutboxEntry{ChatID: 42, MessageID: "m", Parts: testResponse("um", "dois", "três").Parts}
+	if err := outbox.Save(entry); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := outbox.MarkSent(42, "m", 100); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		return okReply(200 + n)
+	})
+	q := newTestQueue(t, New(testToken, WithBaseURL(server.URL), WithReplyChain(true)), WithOutbox(outbox))
+
+	results, err := q.Resume(context.Background())
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Resume returned %d results, want 1", len(results))
+	}
+	result := waitResult(t, results[0])
+	if result.Err != nil {
+		t.Fatalf("send failed: %v", result.Err)
+	}
+	if fmt.Sprint(result.SentIDs) != "[100 200 201]" {
+		t.Errorf("SentIDs = %v, want [100 200 201]", result.SentIDs)
+	}
+
+	requests := api.sent()
+	if len(requests) != 2 || requests[0].Text != "dois" || requests[1].Text != "três" {
+		t.Fatalf("requests = %+v, want only the undelivered parts", requests)
+	}
+	if requests[0].ReplyParameters == nil || requests[0].ReplyParameters.MessageID != 100 {
+		t.Errorf("first resumed part reply_parameters = %+v, want message_id 100", requests[0].ReplyParameters)
+	}
+
+	pending, _ := outbox.Pending()
+	if len(pending) != 0 {
+		t.Errorf("Pending after delivery = %+v, want none", pending)
+	}
+}
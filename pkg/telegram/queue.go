@@ -0,0 +1,421 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+const (
+	// DefaultQueueSize é o número padrão de mensagens aguardando envio
+	DefaultQueueSize = 1000
+	// DefaultQueueWorkers é o número padrão de envios simultâneos
+	DefaultQueueWorkers = 8
+	// DefaultGlobalRate é o limite padrão de mensagens por segundo do bot
+	DefaultGlobalRate = 30
+	// DefaultChatRate é o limite padrão de mensagens por segundo em cada chat
+	DefaultChatRate = 1
+	// DefaultMaxRetries é quantas vezes uma parte é reenviada após um 429
+	DefaultMaxRetries = 5
+)
+
+var (
+	// ErrQueueFull é devolvido por TryEnqueue quando a fila está cheia
+	ErrQueueFull = errors.New("telegram: send queue is full")
+	// ErrQueueClosed é devolvido ao enfileirar depois de Close, e como
+	// resultado das mensagens que não chegaram a ser enviadas
+	ErrQueueClosed = errors.New("telegram: send queue is closed")
+)
+
+// Result é o resultado do envio de uma mensagem enfileirada
+type Result struct {
+	ChatID    int64
+	MessageID string
+	// SentIDs são os IDs das mensagens enviadas, um por parte. Em caso de
+	// erro, contém as partes enviadas antes da falha.
+	SentIDs []int
+	Err     error
+}
+
+type job struct {
+	chatID   int64
+	response types.MessageResponse
+	// sent são os IDs das partes já entregues antes de um reinício
+	sent   []int
+	result chan Result
+	// saved indica que o job foi guardado no outbox por Enqueue
+	saved bool
+}
+
+// Queue envia mensagens em segundo plano respeitando os limites do Telegram:
+// um limite global de mensagens por segundo, um limite por chat e o
+// retry_after das respostas 429.
+//
+// As partes de uma mensagem são enviadas sempre em ordem, e as mensagens de
+// um mesmo chat na ordem em que foram enfileiradas. Quando a fila está cheia,
+// Enqueue espera por espaço.
+type Queue struct {
+	sender     *Sender
+	size       int
+	workers    int
+	globalRate float64
+	chatRate   float64
+	maxRetries int
+	outbox     Outbox
+
+	global *limiter
+	chats  *chatLimiters
+	seed   maphash.Seed
+	queues []chan *job
+
+	// mu protege closed; Enqueue o segura durante a espera por espaço, para
+	// que Close só feche as filas depois que ninguém mais estiver enviando.
+	// closing libera antes quem está esperando.
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+type QueueOption func(*Queue)
+
+// WithQueueSize define quantas mensagens podem aguardar envio
+func WithQueueSize(size int) QueueOption {
+	return func(q *Queue) {
+		if size > 0 {
+			q.size = size
+		}
+	}
+}
+
+// WithQueueWorkers define quantas mensagens, de chats diferentes, são
+// enviadas ao mesmo tempo
+func WithQueueWorkers(workers int) QueueOption {
+	return func(q *Queue) {
+		if workers > 0 {
+			q.workers = workers
+		}
+	}
+}
+
+// WithGlobalRate limita as mensagens por segundo de todo o bot. Com 0, não há
+// limite global.
+func WithGlobalRate(perSecond float64) QueueOption {
+	return func(q *Queue) {
+		q.globalRate = perSecond
+	}
+}
+
+// WithChatRate limita as mensagens por segundo em cada chat. Em grupos o
+// Telegram aceita cerca de 20 por minuto (WithChatRate(20.0 / 60)).
+func WithChatRate(perSecond float64) QueueOption {
+	return func(q *Queue) {
+		q.chatRate = perSecond
+	}
+}
+
+// WithMaxRetries define quantas vezes uma parte é reenviada depois de uma
+// resposta 429
+func WithMaxRetries(retries int) QueueOption {
+	return func(q *Queue) {
+		if retries >= 0 {
+			q.maxRetries = retries
+		}
+	}
+}
+
+// WithOutbox guarda as mensagens no outbox enquanto são enviadas. Depois de
+// um reinício, Resume retoma as partes que faltaram. A entrega é
+// at-least-once: uma parte enviada pouco antes do reinício pode ser repetida.
+func WithOutbox(outbox Outbox) QueueOption {
+	return func(q *Queue) {
+		q.outbox = outbox
+	}
+}
+
+// NewQueue cria a fila e inicia os workers de envio. Feche-a com Close.
+func NewQueue(sender *Sender, options ...QueueOption) *Queue {
+	q := &Queue{
+		sender:     sender,
+		size:       DefaultQueueSize,
+		workers:    DefaultQueueWorkers,
+		globalRate: DefaultGlobalRate,
+		chatRate:   DefaultChatRate,
+		maxRetries: DefaultMaxRetries,
+		seed:       maphash.MakeSeed(),
+		closing:    make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(q)
+	}
+	q.global = newLimiter(q.globalRate)
+	q.chats = &chatLimiters{perSecond: q.chatRate, chats: make(map[int64]*limiter)}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+
+	// Cada chat vai sempre para o mesmo worker, o que mantém a ordem das
+	// mensagens do chat
+	perWorker := max(q.size/q.workers, 1)
+	q.queues = make([]chan *job, q.workers)
+	for i := range q.queues {
+		q.queues[i] = make(chan *job, perWorker)
+		q.wg.Add(1)
+		go q.worker(q.queues[i])
+	}
+	return q
+}
+
+// Enqueue coloca a mensagem na fila, esperando por espaço se ela estiver
+// cheia, e devolve o canal por onde chega o resultado do envio
+func (q *Queue) Enqueue(ctx context.Context, chatID int64, response types.MessageResponse) (<-chan Result, error) {
+	j, err := q.newJob(chatID, response)
+	if err != nil {
+		return nil, err
+	}
+	return q.push(ctx, j, true)
+}
+
+// TryEnqueue é como Enqueue, mas devolve ErrQueueFull em vez de esperar
+func (q *Queue) TryEnqueue(chatID int64, response types.MessageResponse) (<-chan Result, error) {
+	j, err := q.newJob(chatID, response)
+	if err != nil {
+		return nil, err
+	}
+	return q.push(context.Background(), j, false)
+}
+
+// Resume enfileira as mensagens que ficaram no outbox (ver WithOutbox), na
+// ordem em que foram enfileiradas, a partir da primeira parte não entregue.
+// Deve ser chamado antes de enfileirar mensagens novas. Uma mensagem com mais
+// IDs entregues do que partes é recusada com erro.
+func (q *Queue) Resume(ctx context.Context) ([]<-chan Result, error) {
+	if q.outbox == nil {
+		return nil, nil
+	}
+	entries, err := q.outbox.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]<-chan Result, 0, len(entries))
+	for _, entry := range entries {
+		if err := entry.validate(); err != nil {
+			return results, fmt.Errorf("telegram: outbox: %w", err)
+		}
+		j := &job{
+			chatID: entry.ChatID,
+			response: types.MessageResponse{
+				MessageID:  entry.MessageID,
+				TotalParts: len(entry.Parts),
+				Parts:      entry.Parts,
+			},
+			sent:   entry.SentIDs,
+			result: make(chan Result, 1),
+		}
+		result, err := q.push(ctx, j, true)
+		if err != nil {
+			return results, err
+		}
+		q.sender.logger.Info("message resumed from outbox",
+			slog.String("message_id", entry.MessageID),
+			slog.Int64("chat_id", entry.ChatID),
+			slog.Int("sent_parts", len(entry.SentIDs)),
+			slog.Int("parts", len(entry.Parts)))
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (q *Queue) newJob(chatID int64, response types.MessageResponse) (*job, error) {
+	if len(response.Parts) == 0 {
+		return nil, types.NewError(types.ErrInvalidInput, "message has no parts", nil)
+	}
+	j := &job{chatID: chatID, response: response, result: make(chan Result, 1)}
+	if q.outbox != nil {
+		err := q.outbox.Save(&OutboxEntry{
+			ChatID:    chatID,
+			MessageID: response.MessageID,
+			Parts:     response.Parts,
+		})
+		if err != nil {
+			return nil, err
+		}
+		j.saved = true
+	}
+	return j, nil
+}
+
+// push coloca o job na fila do worker do chat. Se não conseguir, a mensagem
+// nova sai do outbox.
+func (q *Queue) push(ctx context.Context, j *job, wait bool) (<-chan Result, error) {
+	err := q.tryPush(ctx, j, wait)
+	if err != nil && j.saved {
+		q.removeFromOutbox(j)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return j.result, nil
+}
+
+func (q *Queue) tryPush(ctx context.Context, j *job, wait bool) error {
+	queue := q.queues[maphash.Comparable(q.seed, j.chatID)%uint64(len(q.queues))]
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if !wait {
+		select {
+		case queue <- j:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	}
+	select {
+	case queue <- j:
+		return nil
+	case <-q.closing:
+		return ErrQueueClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len devolve quantas mensagens aguardam envio
+func (q *Queue) Len() int {
+	n := 0
+	for _, queue := range q.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// Close para de aceitar mensagens e espera o envio das que já estão na fila.
+// Se ctx terminar antes, os envios em andamento são cancelados e as mensagens
+// restantes recebem ErrQueueClosed.
+func (q *Queue) Close(ctx context.Context) error {
+	q.closeOnce.Do(func() { close(q.closing) })
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	for _, queue := range q.queues {
+		close(queue)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker(queue chan *job) {
+	defer q.wg.Done()
+	for j := range queue {
+		if q.ctx.Err() != nil {
+			j.result <- Result{ChatID: j.chatID, MessageID: j.response.MessageID, SentIDs: j.sent, Err: ErrQueueClosed}
+			continue
+		}
+		ids, err := q.deliver(q.ctx, j)
+		j.result <- Result{ChatID: j.chatID, MessageID: j.response.MessageID, SentIDs: ids, Err: err}
+	}
+}
+
+// deliver envia as partes em ordem, a partir da primeira não entregue,
+// esperando os limitadores antes de cada uma e repetindo as recusadas com 429
+// depois do retry_after
+func (q *Queue) deliver(ctx context.Context, j *job) ([]int, error) {
+	s := q.sender
+	response := j.response
+	chat := q.chats.get(j.chatID)
+	ids := make([]int, len(j.sent), len(response.Parts))
+	copy(ids, j.sent)
+
+	for _, part := range response.Parts[len(ids):] {
+		id, err := q.sendPart(ctx, chat, j.chatID, response.MessageID, part, s.replyTo(ids))
+		if err != nil {
+			// Só a recusa definitiva do Telegram tira a mensagem do outbox;
+			// com falhas de rede ou o fim da fila, ela é retomada depois
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				q.removeFromOutbox(j)
+			}
+			return ids, s.partFailed(j.chatID, response.MessageID, part, len(response.Parts), err)
+		}
+		ids = append(ids, id)
+
+		if q.outbox != nil {
+			if err := q.outbox.MarkSent(j.chatID, response.MessageID, id); err != nil {
+				// Sem o registro a parte seria repetida depois de um reinício,
+				// então o envio para aqui
+				return ids, fmt.Errorf("recording part %d of %d: %w", part.Part, len(response.Parts), err)
+			}
+		}
+	}
+	q.removeFromOutbox(j)
+	return ids, nil
+}
+
+func (q *Queue) removeFromOutbox(j *job) {
+	if q.outbox == nil {
+		return
+	}
+	if err := q.outbox.Remove(j.chatID, j.response.MessageID); err != nil {
+		q.sender.logger.Error("failed to remove message from outbox",
+			slog.String("message_id", j.response.MessageID),
+			slog.Int64("chat_id", j.chatID),
+			slog.Any("error", err))
+	}
+}
+
+func (q *Queue) sendPart(ctx context.Context, chat *limiter, chatID int64, messageID string, part types.MessagePart, replyTo int) (int, error) {
+	for attempt := 0; ; attempt++ {
+		if err := chat.wait(ctx); err != nil {
+			return 0, err
+		}
+		if err := q.global.wait(ctx); err != nil {
+			return 0, err
+		}
+
+		id, err := q.sender.sendPart(ctx, chatID, messageID, part, replyTo)
+		var apiErr *APIError
+		if err == nil || !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 || attempt >= q.maxRetries {
+			return id, err
+		}
+
+		q.sender.logger.Warn("telegram flood limit reached",
+			slog.String("message_id", messageID),
+			slog.Int("part", part.Part),
+			slog.Int64("chat_id", chatID),
+			slog.Duration("retry_after", apiErr.RetryAfter),
+			slog.Int("attempt", attempt+1))
+		chat.pause(apiErr.RetryAfter)
+		// O 429 também pode vir do limite do bot inteiro, então os outros
+		// chats esperam um pouco
+		q.global.pause(min(apiErr.RetryAfter, time.Second))
+	}
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+const floodReply = `{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 1", "parameters": {"retry_after": 1}}`
+
+func newTestQueue(t *testing.T, sender *Sender, options ...QueueOption) *Queue {
+	t.Helper()
+	// Sem limites de taxa, para que só o retry_after atrase os envios
+	options = append([]QueueOption{WithGlobalRate(0), WithChatRate(0)}, options...)
+	q := NewQueue(sender, options...)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := q.Close(ctx); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+	return q
+}
+
+func waitResult(t *testing.T, results <-chan Result) Result {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the send result")
+		return Result{}
+	}
+}
+
+func TestQueueHonoursRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if n == 0 {
+			return floodReply
+		}
+		return okReply(100 + n)
+	})
+	q := newTestQueue(t, New(testToken, WithBaseURL(server.URL)))
+
+	results, err := q.Enqueue(context.Background(), 42, testResponse("um", "dois"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	result := waitResult(t, results)
+	if result.Err != nil {
+		t.Fatalf("send failed: %v", result.Err)
+	}
+	if fmt.Sprint(result.SentIDs) != "[101 102]" {
+		t.Errorf("SentIDs = %v, want [101 102]", result.SentIDs)
+	}
+
+	requests := api.sent()
+	if len(requests) != 3 || requests[0].Text != "um" || requests[1].Text != "um" || requests[2].Text != "dois" {
+		t.Fatalf("requests = %+v, want the first part retried before the second", requests)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if wait := times[1].Sub(times[0]); wait < time.Second {
+		t.Errorf("retried after %v, want at least the 1s retry_after", wait)
+	}
+}
+
+func TestQueueGivesUpAfterMaxRetries(t *testing.T) {
+	api, server := newFakeAPI(t, func(int, types.SendMessagePayload) string {
+		return floodReply
+	})
+	q := newTestQueue(t, New(testToken, WithBaseURL(server.URL)), WithMaxRetries(0))
+
+	results, err := q.Enqueue(context.Background(), 42, testResponse("um"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	result := waitResult(t, results)
+
+	var apiErr *APIError
+	if !errors.As(result.Err, &apiErr) || apiErr.RetryAfter != time.Second {
+		t.Fatalf("error = %v, want the 429 with retry_after", result.Err)
+	}
+	if len(api.sent()) != 1 {
+		t.Errorf("got %d requests, want no retry", len(api.sent()))
+	}
+}
+
+func TestQueueRejectsAfterClose(t *testing.T) {
+	q := NewQueue(New(testToken))
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := q.Enqueue(context.Background(), 42, testResponse("um")); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Enqueue after Close = %v, want ErrQueueClosed", err)
+	}
+}
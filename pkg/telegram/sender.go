@@ -0,0 +1,170 @@
+// Package telegram envia as partes de um MessageResponse pela Bot API do
+// Telegram, na ordem, com o parse_mode de cada parte.
+//
+//	sender := telegram.New(token, telegram.WithReplyChain(true))
+//	ids, err := sender.Send(ctx, chatID, response)
+//
+// SendMarkdown também faz a conversão e, se o Telegram recusar a formatação
+// de uma parte, a reenvia com um nível de segurança mais restrito.
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+// DefaultBaseURL é o endereço da Bot API oficial
+const DefaultBaseURL = "https://api.telegram.org"
+
+type Sender struct {
+	token      string
+	baseURL    string
+	client     *http.Client
+	replyChain bool
+	logger     *slog.Logger
+	config     *types.Config
+}
+
+type Option func(*Sender)
+
+// WithBaseURL troca o endereço da Bot API, por exemplo por um servidor local
+// da Bot API ou por um httptest.Server nos testes
+func WithBaseURL(baseURL string) Option {
+	return func(s *Sender) {
+		s.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient define o cliente HTTP usado nas chamadas. O padrão é
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Sender) {
+		if client != nil {
+			s.client = client
+		}
+	}
+}
+
+// WithReplyChain faz cada parte, a partir da segunda, ser enviada como
+// resposta à parte anterior
+func WithReplyChain(enabled bool) Option {
+	return func(s *Sender) {
+		s.replyChain = enabled
+	}
+}
+
+// WithLogger define o logger do envio. Por padrão nada é registrado.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Sender) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithConfig define a configuração usada por SendMarkdown para converter o
+// Markdown. Por padrão usa types.DefaultConfig.
+func WithConfig(config *types.Config) Option {
+	return func(s *Sender) {
+		if config != nil {
+			s.config = config
+		}
+	}
+}
+
+// New cria um Sender para o bot com o token informado
+func New(token string, options ...Option) *Sender {
+	s := &Sender{
+		token:   token,
+		baseURL: DefaultBaseURL,
+		client:  http.DefaultClient,
+		logger:  slog.New(slog.DiscardHandler),
+		config:  types.DefaultConfig(),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// message é a parte da Message da Bot API usada pelo Sender
+type message struct {
+	MessageID int `json:"message_id"`
+}
+
+// Send envia cada parte da resposta ao chat, na ordem, e devolve os IDs das
+// mensagens enviadas. Se uma parte falhar, o envio para e Send devolve os IDs
+// das partes já enviadas junto com o erro.
+func (s *Sender) Send(ctx context.Context, chatID int64, response types.MessageResponse) ([]int, error) {
+	if len(response.Parts) == 0 {
+		return nil, types.NewError(types.ErrInvalidInput, "message has no parts", nil)
+	}
+
+	ids := make([]int, 0, len(response.Parts))
+	for _, part := range response.Parts {
+		id, err := s.sendPart(ctx, chatID, response.MessageID, part, s.replyTo(ids))
+		if err != nil {
+			return ids, s.partFailed(chatID, response.MessageID, part, len(response.Parts), err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// replyTo devolve a mensagem à qual a próxima parte responde, ou 0
+func (s *Sender) replyTo(ids []int) int {
+	if !s.replyChain || len(ids) == 0 {
+		return 0
+	}
+	return ids[len(ids)-1]
+}
+
+// partFailed registra a falha definitiva de uma parte e devolve o erro de Send
+func (s *Sender) partFailed(chatID int64, messageID string, part types.MessagePart, total int, err error) error {
+	s.logger.Error("message part not sent",
+		slog.String("message_id", messageID),
+		slog.Int("part", part.Part),
+		slog.Int64("chat_id", chatID),
+		slog.Any("error", err))
+	return fmt.Errorf("sending part %d of %d: %w", part.Part, total, err)
+}
+
+// sendPart envia uma parte com sendMessage e devolve o ID da mensagem
+func (s *Sender) sendPart(ctx context.Context, chatID int64, messageID string, part types.MessagePart, replyTo int) (int, error) {
+	// O Payload gerado pela conversão traz as demais opções do sendMessage
+	req := types.SendMessagePayload{}
+	if part.Payload != nil {
+		req = *part.Payload
+	}
+	req.ChatID = chatID
+	req.Text = part.Content
+	req.ParseMode = part.ParseMode
+	if req.LinkPreviewOptions == nil {
+		req.LinkPreviewOptions = part.LinkPreview
+	}
+	if req.ReplyMarkup == nil && part.ReplyMarkup != nil {
+		req.ReplyMarkup = part.ReplyMarkup
+	}
+	if replyTo != 0 {
+		req.ReplyParameters = &types.ReplyParameters{
+			MessageID:                replyTo,
+			AllowSendingWithoutReply: true,
+		}
+	}
+
+	var sent message
+	if err := s.call(ctx, "sendMessage", req, &sent); err != nil {
+		return 0, err
+	}
+	s.logger.Debug("message part sent",
+		slog.String("message_id", messageID),
+		slog.Int("part", part.Part),
+		slog.Int64("chat_id", chatID),
+		slog.Int("telegram_message_id", sent.MessageID))
+	return sent.MessageID, nil
+}
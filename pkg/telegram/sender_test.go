@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sshturbo/GoTeleMD/pkg/types"
+)
+
+const testToken = "123456:secret-token"
+
+// fakeAPI é uma Bot API de mentira que registra cada sendMessage recebido e
+// responde com o que reply devolver para a n-ésima chamada (a partir de 0)
+type fakeAPI struct {
+	t     *testing.T
+	reply func(n int, req types.SendMessagePayload) string
+
+	mu       sync.Mutex
+	requests []types.SendMessagePayload
+}
+
+func newFakeAPI(t *testing.T, reply func(n int, req types.SendMessagePayload) string) (*fakeAPI, *httptest.Server) {
+	t.Helper()
+	api := &fakeAPI{t: t, reply: reply}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bot"+testToken+"/sendMessage" {
+		a.t.Errorf("unexpected path %q", r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	var req types.SendMessagePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.t.Errorf("decoding request: %v", err)
+	}
+
+	a.mu.Lock()
+	n := len(a.requests)
+	a.requests = append(a.requests, req)
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, a.reply(n, req))
+}
+
+func (a *fakeAPI) sent() []types.SendMessagePayload {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]types.SendMessagePayload(nil), a.requests...)
+}
+
+func okReply(messageID int) string {
+	return fmt.Sprintf(`{"ok": true, "result": {"message_id": %d}}`, messageID)
+}
+
+func testResponse(contents ...string) types.MessageResponse {
+	response := types.MessageResponse{MessageID: "test", TotalParts: len(contents)}
+	for i, content := range contents {
+		response.Parts = append(response.Parts, types.MessagePart{
+			Part:      i + 1,
+			Content:   content,
+			ParseMode: "MarkdownV2",
+		})
+	}
+	return response
+}
+
+func TestSendChainsReplies(t *testing.T) {
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		return okReply(100 + n)
+	})
+	sender := New(testToken, WithBaseURL(server.URL), WithReplyChain(true))
+
+	ids, err := sender.Send(context.Background(), 42, testResponse("um", "dois", "três"))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if fmt.Sprint(ids) != "[100 101 102]" {
+		t.Fatalf("ids = %v, want [100 101 102]", ids)
+	}
+
+	requests := api.sent()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	if requests[0].ReplyParameters != nil {
+		t.Errorf("first part replies to %d, want no reply", requests[0].ReplyParameters.MessageID)
+	}
+	for i, req := range requests[1:] {
+		want := ids[i]
+		if req.ReplyParameters == nil || req.ReplyParameters.MessageID != want {
+			t.Errorf("part %d reply_parameters = %+v, want message_id %d", i+2, req.ReplyParameters, want)
+		}
+		if req.ChatID != 42 || req.ParseMode != "MarkdownV2" {
+			t.Errorf("part %d chat_id = %d, parse_mode = %q", i+2, req.ChatID, req.ParseMode)
+		}
+	}
+}
+
+func TestSendWithoutReplyChain(t *testing.T) {
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		return okReply(100 + n)
+	})
+	sender := New(testToken, WithBaseURL(server.URL))
+
+	if _, err := sender.Send(context.Background(), 42, testResponse("um", "dois")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	for i, req := range api.sent() {
+		if req.ReplyParameters != nil {
+			t.Errorf("part %d replies to %d, want no reply", i+1, req.ReplyParameters.MessageID)
+		}
+	}
+}
+
+func TestSendWrapsPartError(t *testing.T) {
+	api, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		if n == 1 {
+			return `{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`
+		}
+		return okReply(100 + n)
+	})
+	sender := New(testToken, WithBaseURL(server.URL))
+
+	ids, err := sender.Send(context.Background(), 42, testResponse("um", "dois", "três"))
+	if err == nil {
+		t.Fatal("Send succeeded, want error")
+	}
+	if fmt.Sprint(ids) != "[100]" {
+		t.Errorf("ids = %v, want the parts sent before the failure ([100])", ids)
+	}
+	if len(api.sent()) != 2 {
+		t.Errorf("got %d requests, want sending to stop after the failed part", len(api.sent()))
+	}
+	if !strings.HasPrefix(err.Error(), "sending part 2 of 3: ") {
+		t.Errorf("error = %q, want it to name the failed part", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v does not wrap *APIError", err)
+	}
+	if apiErr.Method != "sendMessage" || apiErr.ErrorCode != 403 {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestSendHidesTokenOnNetworkError(t *testing.T) {
+	_, server := newFakeAPI(t, func(n int, _ types.SendMessagePayload) string {
+		return okReply(1)
+	})
+	server.Close()
+	sender := New(testToken, WithBaseURL(server.URL))
+
+	_, err := sender.Send(context.Background(), 42, testResponse("um"))
+	if err == nil {
+		t.Fatal("Send succeeded against a closed server")
+	}
+	if strings.Contains(err.Error(), testToken) {
+		t.Errorf("error %q leaks the bot token", err)
+	}
+}
+
+func TestSendRejectsEmptyResponse(t *testing.T) {
+	sender := New(testToken)
+	_, err := sender.Send(context.Background(), 42, types.MessageResponse{})
+
+	var convErr *types.Error
+	if !errors.As(err, &convErr) || convErr.Type != types.ErrInvalidInput {
+		t.Errorf("error = %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"regexp"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+)
+
+type (
+	Block     = internal.Block
+	BlockType = internal.BlockType
+)
+
+const (
+	BlockText  = internal.BlockText
+	BlockCode  = internal.BlockCode
+	BlockTable = internal.BlockTable
+	BlockTitle = internal.BlockTitle
+	BlockList  = internal.BlockList
+	BlockQuote = internal.BlockQuote
+	// BlockCustom é o primeiro valor livre para tipos de bloco registrados
+	// pelo usuário
+	BlockCustom = internal.BlockCustom
+)
+
+// BlockRenderer renderiza um bloco já separado pelo tokenizador. O texto
+// devolvido é inserido na mensagem como está, então deve respeitar o modo de
+// saída da configuração (inclusive o escape do MarkdownV2).
+type BlockRenderer interface {
+	RenderBlock(block Block, config *Config) string
+}
+
+// BlockRendererFunc permite usar uma função comum como BlockRenderer
+type BlockRendererFunc func(block Block, config *Config) string
+
+func (f BlockRendererFunc) RenderBlock(block Block, config *Config) string {
+	return f(block, config)
+}
+
+// CustomBlock registra um novo tipo de bloco. Linhas consecutivas que casam
+// com Pattern são agrupadas em um único bloco do tipo Type.
+type CustomBlock struct {
+	Type     BlockType
+	Pattern  *regexp.Regexp
+	Renderer BlockRenderer
+}
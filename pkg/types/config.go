@@ -1,32 +1,176 @@
 package types
 
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// OutputMode define o formato do texto produzido pela conversão
+type OutputMode int
+
+const (
+	// OutputMarkdownV2 gera texto para envio com parse_mode=MarkdownV2
+	OutputMarkdownV2 OutputMode = iota
+	// OutputPlainText gera texto sem marcação, para envio sem parse_mode
+	OutputPlainText
+)
+
+// ParseModeMarkdownV2 é o parse_mode da Bot API para o texto em MarkdownV2
+const ParseModeMarkdownV2 = "MarkdownV2"
+
+// ParseMode devolve o parse_mode da Bot API para o texto gerado no modo, ou
+// "" quando o texto deve ser enviado sem parse_mode
+func (m OutputMode) ParseMode() string {
+	if m == OutputMarkdownV2 {
+		return ParseModeMarkdownV2
+	}
+	return ""
+}
+
+func (m OutputMode) MarshalText() ([]byte, error) {
+	switch m {
+	case OutputMarkdownV2:
+		return []byte("markdownv2"), nil
+	case OutputPlainText:
+		return []byte("plain"), nil
+	}
+	return nil, fmt.Errorf("unknown output mode %d", int(m))
+}
+
+func (m *OutputMode) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "markdownv2":
+		*m = OutputMarkdownV2
+	case "plain":
+		*m = OutputPlainText
+	default:
+		return fmt.Errorf("unknown output mode %q (expected markdownv2 or plain)", text)
+	}
+	return nil
+}
+
+// Config reúne as opções da conversão. As tags json dão o nome de cada campo
+// nos arquivos de configuração e nas variáveis de ambiente (ver LoadConfig);
+// campos com funções ou interfaces só podem ser definidos por código.
 type Config struct {
-	SafetyLevel          int
-	AlignTableColumns    bool
-	IgnoreTableSeparator bool
-	MaxMessageLength     int
-	EnableDebugLogs      bool
-	CustomEscapeChars    []string
-	PreserveEmptyLines   bool
-	StrictLineBreaks     bool
-	NumWorkers           int 
-	WorkerQueueSize      int
-	MaxConcurrentParts   int 
+	SafetyLevel          int                         `json:"safety_level"`
+	BlockSafetyLevels    map[BlockType]int           `json:"block_safety_levels"`
+	AlignTableColumns    bool                        `json:"align_table_columns"`
+	IgnoreTableSeparator bool                        `json:"ignore_table_separator"`
+	MaxMessageLength     int                         `json:"max_message_length"`
+	EnableDebugLogs      bool                        `json:"enable_debug_logs"`
+	CustomEscapeChars    []string                    `json:"custom_escape_chars"`
+	PreserveEmptyLines   bool                        `json:"preserve_empty_lines"`
+	StrictLineBreaks     bool                        `json:"strict_line_breaks"`
+	NumWorkers           int                         `json:"num_workers"`
+	WorkerQueueSize      int                         `json:"worker_queue_size"`
+	MaxConcurrentParts   int                         `json:"max_concurrent_parts"`
+	LinkPolicy           *LinkPolicy                 `json:"link_policy"`
+	OutputMode           OutputMode                  `json:"output_mode"`
+	Renderers            map[BlockType]BlockRenderer `json:"-"`
+	CustomBlocks         []CustomBlock               `json:"-"`
+	InlineExtensions     []InlineExtension           `json:"-"`
+	InputMiddleware      []InputMiddleware           `json:"-"`
+	OutputMiddleware     []OutputMiddleware          `json:"-"`
+	Logger               *slog.Logger                `json:"-"`
+	Metrics              Metrics                     `json:"-"`
+	IncludeStats         bool                        `json:"include_stats"`
+	Payload              *PayloadOptions             `json:"payload"`
+	LinkPreview          LinkPreviewMode             `json:"link_preview"`
+	LinkPreviewURL       string                      `json:"link_preview_url"`
+	ExtractButtons       bool                        `json:"extract_buttons"`
+
+	// Reporter é preenchido pela conversão em uma cópia da configuração para
+	// cada bloco renderizado. Renderizadores personalizados podem usá-lo para
+	// relatar diagnósticos.
+	Reporter DiagnosticReporter `json:"-"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		SafetyLevel:          1, 
+		SafetyLevel:          1,
 		AlignTableColumns:    true,
 		IgnoreTableSeparator: false,
-		MaxMessageLength:     4096, 
+		MaxMessageLength:     4096,
 		EnableDebugLogs:      false,
 		PreserveEmptyLines:   true,
 		StrictLineBreaks:     true,
 		NumWorkers:           4,
 		WorkerQueueSize:      32,
 		MaxConcurrentParts:   8,
+		OutputMode:           OutputMarkdownV2,
+	}
+}
+
+// Log devolve o logger usado pela conversão. Sem Logger definido, os logs são
+// descartados, a não ser que EnableDebugLogs esteja ativo; nesse caso vão
+// para a saída de erro em nível debug.
+func (c *Config) Log() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	if c.EnableDebugLogs {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	}
+	return slog.New(slog.DiscardHandler)
+}
+
+// Clone devolve uma cópia da configuração que pode ser alterada sem afetar a
+// original. Mapas, listas e a política de links são copiados; renderizadores,
+// extensões, logger e métricas continuam compartilhados.
+func (c *Config) Clone() *Config {
+	clone := *c
+	if c.LinkPolicy != nil {
+		policy := *c.LinkPolicy
+		policy.AllowedSchemes = append([]string(nil), c.LinkPolicy.AllowedSchemes...)
+		clone.LinkPolicy = &policy
+	}
+	if c.BlockSafetyLevels != nil {
+		clone.BlockSafetyLevels = make(map[BlockType]int, len(c.BlockSafetyLevels))
+		for blockType, level := range c.BlockSafetyLevels {
+			clone.BlockSafetyLevels[blockType] = level
+		}
+	}
+	if c.Renderers != nil {
+		clone.Renderers = make(map[BlockType]BlockRenderer, len(c.Renderers))
+		for blockType, renderer := range c.Renderers {
+			clone.Renderers[blockType] = renderer
+		}
+	}
+	if c.Payload != nil {
+		payload := *c.Payload
+		if c.Payload.LinkPreview != nil {
+			preview := *c.Payload.LinkPreview
+			payload.LinkPreview = &preview
+		}
+		clone.Payload = &payload
+	}
+	clone.CustomEscapeChars = append([]string(nil), c.CustomEscapeChars...)
+	clone.CustomBlocks = append([]CustomBlock(nil), c.CustomBlocks...)
+	clone.InlineExtensions = append([]InlineExtension(nil), c.InlineExtensions...)
+	clone.InputMiddleware = append([]InputMiddleware(nil), c.InputMiddleware...)
+	clone.OutputMiddleware = append([]OutputMiddleware(nil), c.OutputMiddleware...)
+	return &clone
+}
+
+// SafetyLevelFor devolve o nível de segurança de um tipo de bloco: o definido
+// em BlockSafetyLevels ou, se não houver, SafetyLevel
+func (c *Config) SafetyLevelFor(blockType BlockType) int {
+	if level, ok := c.BlockSafetyLevels[blockType]; ok {
+		return level
+	}
+	return c.SafetyLevel
+}
+
+// MetricsOrNop devolve o Metrics da configuração, ou uma implementação que
+// descarta tudo quando nenhum foi definido
+func (c *Config) MetricsOrNop() Metrics {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return nopMetrics{}
 }
 
 type Option func(*Config)
@@ -37,6 +181,17 @@ func WithSafetyLevel(level int) Option {
 	}
 }
 
+// WithBlockSafetyLevel define o nível de segurança de um tipo de bloco,
+// sobrepondo SafetyLevel apenas para ele
+func WithBlockSafetyLevel(blockType BlockType, level int) Option {
+	return func(c *Config) {
+		if c.BlockSafetyLevels == nil {
+			c.BlockSafetyLevels = make(map[BlockType]int)
+		}
+		c.BlockSafetyLevels[blockType] = level
+	}
+}
+
 func WithTableAlignment(align bool) Option {
 	return func(c *Config) {
 		c.AlignTableColumns = align
@@ -79,6 +234,28 @@ func WithStrictLineBreaks(strict bool) Option {
 	}
 }
 
+// WithLogger define o logger da conversão. O nível dos logs é controlado pelo
+// próprio handler, independentemente de EnableDebugLogs.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithMetrics define onde as métricas da conversão são registradas
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+// WithStats inclui as estatísticas da conversão em MessageResponse.Stats
+func WithStats(enable bool) Option {
+	return func(c *Config) {
+		c.IncludeStats = enable
+	}
+}
+
 func WithNumWorkers(num int) Option {
 	return func(c *Config) {
 		if num > 0 {
@@ -102,3 +279,63 @@ func WithMaxConcurrentParts(max int) Option {
 		}
 	}
 }
+
+// WithLinkPolicy define a política aplicada às URLs dos links. Com nil, as URLs
+// são mantidas como vieram no Markdown.
+func WithLinkPolicy(policy *LinkPolicy) Option {
+	return func(c *Config) {
+		c.LinkPolicy = policy
+	}
+}
+
+func WithOutputMode(mode OutputMode) Option {
+	return func(c *Config) {
+		c.OutputMode = mode
+	}
+}
+
+// WithBlockRenderer substitui o renderizador usado para um tipo de bloco
+func WithBlockRenderer(blockType BlockType, renderer BlockRenderer) Option {
+	return func(c *Config) {
+		if c.Renderers == nil {
+			c.Renderers = make(map[BlockType]BlockRenderer)
+		}
+		c.Renderers[blockType] = renderer
+	}
+}
+
+// WithCustomBlock registra um novo tipo de bloco e o seu renderizador
+func WithCustomBlock(block CustomBlock) Option {
+	return func(c *Config) {
+		c.CustomBlocks = append(c.CustomBlocks, block)
+		if block.Renderer != nil {
+			WithBlockRenderer(block.Type, block.Renderer)(c)
+		}
+	}
+}
+
+// WithInlineExtension registra uma extensão de sintaxe inline. As extensões
+// são avaliadas na ordem de registro, logo depois do código inline.
+func WithInlineExtension(ext InlineExtension) Option {
+	return func(c *Config) {
+		if ext.Pattern != nil && ext.Render != nil {
+			c.InlineExtensions = append(c.InlineExtensions, ext)
+		}
+	}
+}
+
+// WithInputMiddleware registra middlewares executadas, na ordem, sobre o
+// Markdown de entrada
+func WithInputMiddleware(middleware ...InputMiddleware) Option {
+	return func(c *Config) {
+		c.InputMiddleware = append(c.InputMiddleware, middleware...)
+	}
+}
+
+// WithOutputMiddleware registra middlewares executadas, na ordem, sobre cada
+// parte convertida
+func WithOutputMiddleware(middleware ...OutputMiddleware) Option {
+	return func(c *Config) {
+		c.OutputMiddleware = append(c.OutputMiddleware, middleware...)
+	}
+}
@@ -0,0 +1,14 @@
+package types
+
+import "testing"
+
+func TestSafetyLevelFor(t *testing.T) {
+	config := DefaultConfig()
+	WithBlockSafetyLevel(BlockQuote, 2)(config)
+	if got := config.SafetyLevelFor(BlockQuote); got != 2 {
+		t.Errorf("SafetyLevelFor(quote) = %d, want 2", got)
+	}
+	if got := config.SafetyLevelFor(BlockText); got != config.SafetyLevel {
+		t.Errorf("SafetyLevelFor(text) = %d, want %d", got, config.SafetyLevel)
+	}
+}
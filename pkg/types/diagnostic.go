@@ -0,0 +1,74 @@
+package types
+
+import "encoding/json"
+
+// Severity indica a gravidade de um diagnóstico
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	switch name {
+	case "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		*s = SeverityInfo
+	}
+	return nil
+}
+
+// Códigos dos diagnósticos gerados pela conversão
+const (
+	DiagUnbalancedMarker  = "unbalanced-marker"
+	DiagUnclosedCodeBlock = "unclosed-code-block"
+	DiagLinkDisallowed    = "link-disallowed"
+	DiagInvalidMentionID  = "invalid-mention-id"
+	DiagInvalidEmojiID    = "invalid-emoji-id"
+	DiagTableRowDropped   = "table-row-dropped"
+	DiagTableRowPadded    = "table-row-padded"
+	DiagBlockRenderFailed = "block-render-failed"
+	DiagButtonDropped     = "button-dropped"
+)
+
+// Diagnostic descreve um problema encontrado na entrada. Line e Column são
+// contados a partir de 1 sobre o Markdown convertido, isto é, depois das
+// middlewares de entrada (Column em runes); zero indica posição desconhecida.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Message  string   `json:"message"`
+}
+
+// DiagnosticReporter recebe os diagnósticos gerados durante a renderização de
+// um bloco. offset é a posição em bytes dentro do conteúdo do bloco, ou -1
+// quando o problema se refere ao bloco inteiro.
+type DiagnosticReporter interface {
+	Report(offset int, severity Severity, code, message string)
+}
@@ -2,6 +2,10 @@ package types
 
 import "fmt"
 
+// ErrorType classifica os erros da biblioteca. Cada valor também é um error,
+// servindo de sentinela para errors.Is:
+//
+//	if errors.Is(err, types.ErrInvalidInput) { ... }
 type ErrorType int
 
 const (
@@ -9,8 +13,26 @@ const (
 	ErrInvalidFormat
 	ErrMessageTooLong
 	ErrProcessingFailed
+	ErrInvalidConfig
 )
 
+func (t ErrorType) Error() string {
+	switch t {
+	case ErrInvalidInput:
+		return "invalid input"
+	case ErrInvalidFormat:
+		return "invalid format"
+	case ErrMessageTooLong:
+		return "message too long"
+	case ErrProcessingFailed:
+		return "processing failed"
+	case ErrInvalidConfig:
+		return "invalid configuration"
+	default:
+		return fmt.Sprintf("error type %d", int(t))
+	}
+}
+
 type Error struct {
 	Type    ErrorType
 	Message string
@@ -24,6 +46,23 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// Is permite comparar o erro com a sentinela do seu tipo, ou com outro *Error
+// do mesmo tipo
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorType:
+		return e.Type == t
+	case *Error:
+		return t != nil && e.Type == t.Type
+	default:
+		return false
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 func NewError(errType ErrorType, message string, err error) error {
 	return &Error{
 		Type:    errType,
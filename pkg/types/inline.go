@@ -0,0 +1,13 @@
+package types
+
+import "regexp"
+
+// InlineExtension adiciona uma sintaxe inline própria, como {{ticket:ABC-123}}.
+// Render recebe os submatches de Pattern e devolve o trecho já pronto para o
+// modo de saída da configuração: o texto devolvido é inserido como está,
+// enquanto todo o texto ao redor continua sendo escapado normalmente.
+type InlineExtension struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Render  func(match []string, config *Config) string
+}
@@ -0,0 +1,26 @@
+package types
+
+// InlineKeyboardMarkup é o reply_markup de um teclado inline da Bot API
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton é um botão do teclado inline: abre URL ou envia
+// CallbackData ao bot
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// MaxCallbackDataSize é o limite da Bot API para callback_data, em bytes
+const MaxCallbackDataSize = 64
+
+// WithButtonExtraction transforma o último parágrafo do Markdown, quando ele
+// só tem links de botão ([Abrir](button:https://...) ou
+// [Repetir](callback:retry)), em um teclado inline na última parte
+func WithButtonExtraction(enable bool) Option {
+	return func(c *Config) {
+		c.ExtractButtons = enable
+	}
+}
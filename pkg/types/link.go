@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// LinkAction define o que fazer com um link cuja URL foi recusada pela política
+type LinkAction int
+
+const (
+	// LinkKeepText mantém apenas o texto do link, sem a URL
+	LinkKeepText LinkAction = iota
+	// LinkDrop remove o link por completo, incluindo o texto
+	LinkDrop
+	// LinkShowURLAsCode mantém o texto e exibe a URL original como código
+	LinkShowURLAsCode
+)
+
+func (a LinkAction) MarshalText() ([]byte, error) {
+	switch a {
+	case LinkKeepText:
+		return []byte("keep_text"), nil
+	case LinkDrop:
+		return []byte("drop"), nil
+	case LinkShowURLAsCode:
+		return []byte("show_url_as_code"), nil
+	}
+	return nil, fmt.Errorf("unknown link action %d", int(a))
+}
+
+func (a *LinkAction) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "keep_text":
+		*a = LinkKeepText
+	case "drop":
+		*a = LinkDrop
+	case "show_url_as_code":
+		*a = LinkShowURLAsCode
+	default:
+		return fmt.Errorf("unknown link action %q (expected keep_text, drop or show_url_as_code)", text)
+	}
+	return nil
+}
+
+// LinkPolicy controla quais URLs podem virar links na mensagem convertida.
+// Rewrite é chamado para cada URL aceita e pode devolver outra URL (por
+// exemplo, sem parâmetros de rastreamento) ou nil para recusá-la.
+type LinkPolicy struct {
+	AllowedSchemes []string                  `json:"allowed_schemes"`
+	OnDisallowed   LinkAction                `json:"on_disallowed"`
+	Rewrite        func(u *url.URL) *url.URL `json:"-"`
+}
+
+func DefaultLinkPolicy() *LinkPolicy {
+	return &LinkPolicy{
+		AllowedSchemes: []string{"http", "https", "tg", "mailto"},
+		OnDisallowed:   LinkKeepText,
+	}
+}
+
+// Resolve sanitiza a URL e aplica a política. Devolve a URL final e se ela
+// pode ser usada como link.
+func (p *LinkPolicy) Resolve(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+	if raw == "" || strings.IndexFunc(raw, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0 {
+		return "", false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !p.allows(u) {
+		return "", false
+	}
+
+	if p.Rewrite == nil {
+		return raw, true
+	}
+
+	u = p.Rewrite(u)
+	if u == nil || !p.allows(u) {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func (p *LinkPolicy) allows(u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "" {
+		return false
+	}
+	if (scheme == "http" || scheme == "https") && u.Host == "" {
+		return false
+	}
+	if len(p.AllowedSchemes) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedSchemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestLinkPolicyResolve(t *testing.T) {
+	stripQuery := func(u *url.URL) *url.URL {
+		u.RawQuery = ""
+		return u
+	}
+	reject := func(*url.URL) *url.URL { return nil }
+	toJS := func(u *url.URL) *url.URL {
+		u.Scheme = "javascript"
+		return u
+	}
+
+	tests := []struct {
+		name   string
+		policy *LinkPolicy
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"https allowed", DefaultLinkPolicy(), "https://ex.com/a", "https://ex.com/a", true},
+		{"scheme is case-insensitive", DefaultLinkPolicy(), "HTTPS://ex.com", "HTTPS://ex.com", true},
+		{"tg allowed", DefaultLinkPolicy(), "tg://resolve?domain=bot", "tg://resolve?domain=bot", true},
+		{"mailto allowed", DefaultLinkPolicy(), "mailto:a@ex.com", "mailto:a@ex.com", true},
+		{"javascript rejected", DefaultLinkPolicy(), "javascript:alert(1)", "", false},
+		{"data rejected", DefaultLinkPolicy(), "data:text/html,oi", "", false},
+		{"relative rejected", DefaultLinkPolicy(), "/caminho", "", false},
+		{"http without host", DefaultLinkPolicy(), "http:///x", "", false},
+		{"angle brackets trimmed", DefaultLinkPolicy(), " <https://ex.com> ", "https://ex.com", true},
+		{"inner space rejected", DefaultLinkPolicy(), "https://ex.com/a b", "", false},
+		{"control char rejected", DefaultLinkPolicy(), "https://ex.com/\x00", "", false},
+		{"empty", DefaultLinkPolicy(), "  ", "", false},
+		{"custom allowlist", &LinkPolicy{AllowedSchemes: []string{"https"}}, "http://ex.com", "", false},
+		{"empty allowlist allows any scheme", &LinkPolicy{}, "ftp://ex.com", "ftp://ex.com", true},
+		{"rewrite", &LinkPolicy{Rewrite: stripQuery}, "https://ex.com/a?utm=1", "https://ex.com/a", true},
+		{"rewrite rejects", &LinkPolicy{Rewrite: reject}, "https://ex.com", "", false},
+		{"rewrite result is checked", &LinkPolicy{AllowedSchemes: []string{"https"}, Rewrite: toJS}, "https://ex.com", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.policy.Resolve(tt.raw)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Resolve(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package types
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix é o prefixo das variáveis de ambiente lidas por LoadEnv. O nome de
+// cada variável é a chave do campo em maiúsculas, com "_" no lugar de ".":
+// GOTELEMD_MAX_MESSAGE_LENGTH, GOTELEMD_LINK_POLICY_ON_DISALLOWED...
+const EnvPrefix = "GOTELEMD_"
+
+// LoadConfig monta uma configuração a partir dos valores padrão, do arquivo
+// em path (se não for vazio), das variáveis de ambiente GOTELEMD_* e das
+// opções, nessa ordem: cada etapa sobrescreve o que a anterior definiu. O
+// resultado final passa por Validate.
+func LoadConfig(path string, options ...Option) (*Config, error) {
+	config := DefaultConfig()
+	if path != "" {
+		if err := config.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.LoadEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	for _, opt := range options {
+		opt(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// LoadFile aplica um arquivo de configuração JSON (.json) ou YAML (.yaml,
+// .yml) sobre a configuração
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NewError(ErrInvalidConfig, "failed to read config file", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return c.loadJSON(data, path)
+	case ".yaml", ".yml":
+		return c.loadYAML(data, path)
+	default:
+		return NewError(ErrInvalidConfig, fmt.Sprintf("%s: unsupported config file extension", path), nil)
+	}
+}
+
+// LoadJSON aplica uma configuração em JSON. Apenas as chaves presentes são
+// alteradas.
+func (c *Config) LoadJSON(data []byte) error {
+	return c.loadJSON(data, "json")
+}
+
+// LoadYAML aplica uma configuração em um subconjunto simples de YAML: pares
+// chave: valor, mapas aninhados por indentação, listas em linhas "- item" ou
+// [a, b], strings com ou sem aspas e comentários com #.
+func (c *Config) LoadYAML(data []byte) error {
+	return c.loadYAML(data, "yaml")
+}
+
+// LoadEnv aplica as variáveis GOTELEMD_* de environ, no formato de
+// os.Environ. Listas são separadas por vírgula.
+func (c *Config) LoadEnv(environ []string) error {
+	names := make(map[string]string)
+	for _, key := range ConfigKeys() {
+		names[EnvPrefix+strings.ToUpper(strings.ReplaceAll(key, ".", "_"))] = key
+	}
+
+	values := make(map[string]string)
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key, ok := names[name]
+		if !ok {
+			return NewError(ErrInvalidConfig, fmt.Sprintf("env: unknown variable %s", name), nil)
+		}
+		values[key] = value
+	}
+	return c.loadMap(values, "env")
+}
+
+// LoadMap aplica valores em texto indexados pela chave completa do campo
+// ("max_message_length", "link_policy.allowed_schemes"). Listas são separadas
+// por vírgula e mapas escritos como "chave=valor,chave=valor", como nas
+// variáveis de ambiente.
+func (c *Config) LoadMap(values map[string]string) error {
+	return c.loadMap(values, "values")
+}
+
+// ConfigKeys lista as chaves de todos os campos que podem ser carregados de
+// arquivos, do ambiente ou de LoadMap
+func ConfigKeys() []string {
+	keys := configKeys(reflect.TypeOf(Config{}), "")
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Config) loadMap(values map[string]string, source string) error {
+	nested := make(map[string]any)
+	for key, value := range values {
+		setPath(nested, strings.Split(key, "."), value)
+	}
+	return c.apply(nested, source)
+}
+
+func (c *Config) loadJSON(data []byte, source string) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return NewError(ErrInvalidConfig, fmt.Sprintf("%s: invalid JSON", source), err)
+	}
+	return c.apply(values, source)
+}
+
+func (c *Config) loadYAML(data []byte, source string) error {
+	values, err := parseYAML(string(data))
+	if err != nil {
+		return NewError(ErrInvalidConfig, fmt.Sprintf("%s: invalid YAML", source), err)
+	}
+	return c.apply(values, source)
+}
+
+func (c *Config) apply(values map[string]any, source string) error {
+	return applyStruct(reflect.ValueOf(c).Elem(), values, "", source)
+}
+
+// configFields devolve o índice de cada campo configurável pelo nome da tag
+func configFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// configKeys lista as chaves de todos os valores configuráveis, com os campos
+// de structs aninhadas no formato "pai.filho"
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for name, i := range configFields(t) {
+		field := t.Field(i).Type
+		if field.Kind() == reflect.Ptr && field.Elem().Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Elem(), prefix+name+".")...)
+			continue
+		}
+		keys = append(keys, prefix+name)
+	}
+	return keys
+}
+
+func setPath(values map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := values[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			values[key] = child
+		}
+		values = child
+	}
+	values[path[len(path)-1]] = value
+}
+
+func applyStruct(v reflect.Value, values map[string]any, prefix, source string) error {
+	fields := configFields(v.Type())
+
+	// Ordena as chaves para que os erros sejam sempre os mesmos
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		i, ok := fields[key]
+		if !ok {
+			return NewError(ErrInvalidConfig, fmt.Sprintf("%s: unknown key %q", source, prefix+key), nil)
+		}
+		if err := applyField(v.Field(i), values[key], prefix+key, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyField(field reflect.Value, raw any, key, source string) error {
+	invalid := func(err error) error {
+		return NewError(ErrInvalidConfig, fmt.Sprintf("%s: invalid value for %q", source, key), err)
+	}
+
+	if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return invalid(fmt.Errorf("expected a mapping"))
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+			// Uma política nova parte da padrão, para que definir só
+			// link_policy.on_disallowed não deixe a lista de esquemas vazia
+			if policy, ok := field.Interface().(*LinkPolicy); ok {
+				*policy = *DefaultLinkPolicy()
+			}
+		}
+		return applyStruct(field.Elem(), values, key+".", source)
+	}
+
+	if field.Kind() == reflect.Map {
+		return applyMap(field, raw, key, source)
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+		items, err := stringList(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	text, err := scalarString(raw)
+	if err != nil {
+		return invalid(err)
+	}
+
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(text)); err != nil {
+			return invalid(err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return invalid(err)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return invalid(err)
+		}
+		field.SetInt(n)
+	case reflect.String:
+		field.SetString(text)
+	default:
+		return invalid(fmt.Errorf("unsupported field type %s", field.Type()))
+	}
+	return nil
+}
+
+// applyMap acrescenta as entradas ao mapa. Vindo do ambiente, as entradas são
+// pares chave=valor separados por vírgula.
+func applyMap(field reflect.Value, raw any, key, source string) error {
+	values, ok := raw.(map[string]any)
+	if text, isText := raw.(string); isText {
+		values, ok = make(map[string]any), true
+		for _, pair := range strings.Split(text, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			name, value, found := strings.Cut(pair, "=")
+			if !found {
+				return NewError(ErrInvalidConfig, fmt.Sprintf("%s: invalid value for %q", source, key),
+					fmt.Errorf("expected key=value, got %q", pair))
+			}
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	if !ok {
+		return NewError(ErrInvalidConfig, fmt.Sprintf("%s: invalid value for %q", source, key),
+			fmt.Errorf("expected a mapping"))
+	}
+
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+	for name, value := range values {
+		entryKey := reflect.New(field.Type().Key())
+		if err := applyField(entryKey.Elem(), name, key, source); err != nil {
+			return err
+		}
+		entry := reflect.New(field.Type().Elem())
+		if err := applyField(entry.Elem(), value, key+"."+name, source); err != nil {
+			return err
+		}
+		field.SetMapIndex(entryKey.Elem(), entry.Elem())
+	}
+	return nil
+}
+
+// scalarString converte um valor simples de qualquer origem para texto
+func scalarString(raw any) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("expected a single value")
+	}
+}
+
+// stringList aceita uma lista ou, vindo do ambiente, um texto separado por
+// vírgulas
+func stringList(raw any) ([]string, error) {
+	items := []string{}
+	switch v := raw.(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			text, err := scalarString(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, text)
+		}
+	default:
+		return nil, fmt.Errorf("expected a list")
+	}
+	return items, nil
+}
@@ -0,0 +1,255 @@
+package types
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"document marker", "---\na: 1", map[string]any{"a": "1"}},
+		{"scalars", "a: 1\nb: texto livre", map[string]any{"a": "1", "b": "texto livre"}},
+		{"double quoted", `a: "x: \"y\" # z"`, map[string]any{"a": `x: "y" # z`}},
+		{"single quoted", "a: 'it''s'", map[string]any{"a": "it's"}},
+		{"comments", "# topo\na: 1 # fim\nb: c#d", map[string]any{"a": "1", "b": "c#d"}},
+		{"flow list", "a: [x, 'y', \"z\"]", map[string]any{"a": []any{"x", "y", "z"}}},
+		{"empty flow list", "a: []", map[string]any{"a": []any{}}},
+		{"block list", "a:\n  - x\n  - y\nb: 2", map[string]any{"a": []any{"x", "y"}, "b": "2"}},
+		{"nested mapping", "a:\n  b: 1\n  c:\n    d: 2\ne: 3", map[string]any{
+			"a": map[string]any{"b": "1", "c": map[string]any{"d": "2"}},
+			"e": "3",
+		}},
+		{"key without value", "a:\nb: 1", map[string]any{"a": "", "b": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.input)
+			if err != nil {
+				t.Fatalf("parseYAML(%q) error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"tab indentation", "a:\n\tb: 1", "line 2: tabs"},
+		{"missing colon", "a", "line 1: expected"},
+		{"duplicate key", "a: 1\na: 2", `line 2: duplicate key "a"`},
+		{"unexpected indentation", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"list at top level", "- a", "line 1: unexpected list item"},
+		{"unterminated list", "a: [x, y", "line 1: unterminated list"},
+		{"unterminated string", `a: "x`, "line 1: unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseYAML(%q) error = %v, want it to contain %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigLoadSources(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(*Config) error
+	}{
+		{"json", func(c *Config) error {
+			return c.LoadJSON([]byte(`{"safety_level": 2, "strict_line_breaks": false, "output_mode": "plain",
+				"custom_escape_chars": ["@"], "link_policy": {"allowed_schemes": ["https"], "on_disallowed": "drop"}}`))
+		}},
+		{"yaml", func(c *Config) error {
+			return c.LoadYAML([]byte("safety_level: 2\nstrict_line_breaks: false\noutput_mode: PLAIN\n" +
+				"custom_escape_chars: ['@']\nlink_policy:\n  allowed_schemes:\n    - https\n  on_disallowed: drop\n"))
+		}},
+		{"env", func(c *Config) error {
+			return c.LoadEnv([]string{
+				"HOME=/root",
+				"GOTELEMD_SAFETY_LEVEL=2",
+				"GOTELEMD_STRICT_LINE_BREAKS=false",
+				"GOTELEMD_OUTPUT_MODE=plain",
+				"GOTELEMD_CUSTOM_ESCAPE_CHARS=@",
+				"GOTELEMD_LINK_POLICY_ALLOWED_SCHEMES=https",
+				"GOTELEMD_LINK_POLICY_ON_DISALLOWED=drop",
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			if err := tt.load(config); err != nil {
+				t.Fatalf("load error: %v", err)
+			}
+			if config.SafetyLevel != 2 || config.StrictLineBreaks || config.OutputMode != OutputPlainText {
+				t.Errorf("scalars = %d, %v, %v; want 2, false, plain", config.SafetyLevel, config.StrictLineBreaks, config.OutputMode)
+			}
+			if !reflect.DeepEqual(config.CustomEscapeChars, []string{"@"}) {
+				t.Errorf("CustomEscapeChars = %q, want [@]", config.CustomEscapeChars)
+			}
+			if config.LinkPolicy == nil || !reflect.DeepEqual(config.LinkPolicy.AllowedSchemes, []string{"https"}) ||
+				config.LinkPolicy.OnDisallowed != LinkDrop {
+				t.Errorf("LinkPolicy = %+v, want https only and drop", config.LinkPolicy)
+			}
+			// Campos ausentes na fonte mantêm o valor padrão
+			if config.MaxMessageLength != 4096 || !config.AlignTableColumns {
+				t.Errorf("untouched fields changed: %d, %v", config.MaxMessageLength, config.AlignTableColumns)
+			}
+		})
+	}
+}
+
+func TestLoadPartialLinkPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(*Config) error
+	}{
+		{"json", func(c *Config) error { return c.LoadJSON([]byte(`{"link_policy": {"on_disallowed": "drop"}}`)) }},
+		{"yaml", func(c *Config) error { return c.LoadYAML([]byte("link_policy:\n  on_disallowed: drop\n")) }},
+		{"env", func(c *Config) error { return c.LoadEnv([]string{"GOTELEMD_LINK_POLICY_ON_DISALLOWED=drop"}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			if config.LinkPolicy != nil {
+				t.Fatalf("DefaultConfig().LinkPolicy = %+v, want nil", config.LinkPolicy)
+			}
+			if err := tt.load(config); err != nil {
+				t.Fatalf("load error: %v", err)
+			}
+			// A política criada pela fonte parte da padrão
+			if config.LinkPolicy == nil || config.LinkPolicy.OnDisallowed != LinkDrop ||
+				!reflect.DeepEqual(config.LinkPolicy.AllowedSchemes, DefaultLinkPolicy().AllowedSchemes) {
+				t.Errorf("LinkPolicy = %+v, want the default schemes and drop", config.LinkPolicy)
+			}
+		})
+	}
+}
+
+func TestEnvListSeparator(t *testing.T) {
+	config := DefaultConfig()
+	if err := config.LoadEnv([]string{"GOTELEMD_CUSTOM_ESCAPE_CHARS= @ ,, $ "}); err != nil {
+		t.Fatalf("LoadEnv error: %v", err)
+	}
+	if !reflect.DeepEqual(config.CustomEscapeChars, []string{"@", "$"}) {
+		t.Errorf("CustomEscapeChars = %q, want [@ $]", config.CustomEscapeChars)
+	}
+}
+
+func TestConfigLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(*Config) error
+		want string
+	}{
+		{"unknown json key", func(c *Config) error { return c.LoadJSON([]byte(`{"safety": 1}`)) }, `json: unknown key "safety"`},
+		{"unknown nested key", func(c *Config) error {
+			return c.LoadJSON([]byte(`{"link_policy": {"schemes": []}}`))
+		}, `json: unknown key "link_policy.schemes"`},
+		{"code-only field", func(c *Config) error { return c.LoadJSON([]byte(`{"Logger": null}`)) }, `unknown key "Logger"`},
+		{"unknown yaml key", func(c *Config) error { return c.LoadYAML([]byte("foo: 1")) }, `yaml: unknown key "foo"`},
+		{"unknown env var", func(c *Config) error {
+			return c.LoadEnv([]string{"GOTELEMD_FOO=1"})
+		}, "env: unknown variable GOTELEMD_FOO"},
+		{"invalid int", func(c *Config) error { return c.LoadYAML([]byte("safety_level: alto")) }, `yaml: invalid value for "safety_level"`},
+		{"invalid bool", func(c *Config) error {
+			return c.LoadEnv([]string{"GOTELEMD_INCLUDE_STATS=talvez"})
+		}, `env: invalid value for "include_stats"`},
+		{"invalid enum", func(c *Config) error { return c.LoadJSON([]byte(`{"output_mode": "html"}`)) }, `invalid value for "output_mode"`},
+		{"list for scalar", func(c *Config) error { return c.LoadJSON([]byte(`{"num_workers": [1]}`)) }, `invalid value for "num_workers"`},
+		{"scalar for mapping", func(c *Config) error { return c.LoadJSON([]byte(`{"link_policy": "drop"}`)) }, `invalid value for "link_policy"`},
+		{"invalid json", func(c *Config) error { return c.LoadJSON([]byte(`{`)) }, "json: invalid JSON"},
+		{"invalid yaml", func(c *Config) error { return c.LoadYAML([]byte("a")) }, "yaml: invalid YAML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.load(DefaultConfig())
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("error = %v, want ErrInvalidConfig", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gotelemd.yml")
+	if err := os.WriteFile(path, []byte("safety_level: 0\nmax_message_length: 1000\nnum_workers: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOTELEMD_MAX_MESSAGE_LENGTH", "2000")
+
+	config, err := LoadConfig(path, func(c *Config) { c.NumWorkers = 8 })
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	// Arquivo sobre os padrões, ambiente sobre o arquivo e opções por último
+	if config.SafetyLevel != 0 || config.MaxMessageLength != 2000 || config.NumWorkers != 8 {
+		t.Errorf("config = safety %d, length %d, workers %d; want 0, 2000, 8",
+			config.SafetyLevel, config.MaxMessageLength, config.NumWorkers)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	unsupported := filepath.Join(dir, "gotelemd.toml")
+	if err := os.WriteFile(unsupported, []byte("safety_level = 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{unsupported, filepath.Join(dir, "missing.json")} {
+		if err := DefaultConfig().LoadFile(path); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("LoadFile(%s) error = %v, want ErrInvalidConfig", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestLoadBlockSafetyLevels(t *testing.T) {
+	want := map[BlockType]int{BlockQuote: 2, BlockTable: 0}
+	tests := []struct {
+		name string
+		load func(*Config) error
+	}{
+		{"json", func(c *Config) error { return c.LoadJSON([]byte(`{"block_safety_levels": {"quote": 2, "table": 0}}`)) }},
+		{"yaml", func(c *Config) error { return c.LoadYAML([]byte("block_safety_levels:\n  quote: 2\n  table: 0\n")) }},
+		{"env", func(c *Config) error {
+			return c.LoadEnv([]string{"GOTELEMD_BLOCK_SAFETY_LEVELS=quote=2, table=0"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			if err := tt.load(config); err != nil {
+				t.Fatalf("load error: %v", err)
+			}
+			if !reflect.DeepEqual(config.BlockSafetyLevels, want) {
+				t.Errorf("BlockSafetyLevels = %v, want %v", config.BlockSafetyLevels, want)
+			}
+		})
+	}
+
+	for _, env := range []string{"GOTELEMD_BLOCK_SAFETY_LEVELS=quote", "GOTELEMD_BLOCK_SAFETY_LEVELS=rodape=1"} {
+		if err := DefaultConfig().LoadEnv([]string{env}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("LoadEnv(%q) error = %v, want ErrInvalidConfig", env, err)
+		}
+	}
+}
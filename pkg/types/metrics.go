@@ -0,0 +1,47 @@
+package types
+
+// Nomes das métricas enviadas ao Metrics. Durações são observadas em segundos.
+const (
+	// Histograma com a duração total de cada conversão
+	MetricConvertDuration = "gotelemd_convert_duration_seconds"
+	// Histograma com o tempo de separação das partes em blocos
+	MetricTokenizeDuration = "gotelemd_tokenize_duration_seconds"
+	// Histograma com o tempo de renderização de cada bloco (rótulo block_type)
+	MetricRenderDuration = "gotelemd_render_duration_seconds"
+	// Histograma com o tempo de divisão do texto em partes (rótulo phase:
+	// "input" para a entrada, "output" para o texto convertido)
+	MetricSplitDuration = "gotelemd_split_duration_seconds"
+	// Histograma com o número de partes de cada mensagem
+	MetricMessageParts = "gotelemd_message_parts"
+
+	// Contador de conversões (rótulo result: "ok" ou "error")
+	MetricConversions = "gotelemd_conversions_total"
+	// Contador de blocos renderizados (rótulo block_type)
+	MetricBlocks = "gotelemd_blocks_total"
+	// Contador de caracteres escapados na saída (rótulo block_type)
+	MetricEscapedChars = "gotelemd_escaped_chars_total"
+	// Contador de blocos que falharam e foram enviados como texto simples
+	// (rótulo block_type)
+	MetricBlockFallbacks = "gotelemd_block_fallbacks_total"
+)
+
+// Label é um rótulo de uma métrica
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Metrics recebe as métricas da conversão. As chamadas podem vir de várias
+// goroutines ao mesmo tempo, então a implementação deve ser segura para uso
+// concorrente.
+type Metrics interface {
+	// Count soma value ao contador name
+	Count(name string, value int64, labels ...Label)
+	// Observe registra uma amostra no histograma name
+	Observe(name string, value float64, labels ...Label)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Count(string, int64, ...Label)     {}
+func (nopMetrics) Observe(string, float64, ...Label) {}
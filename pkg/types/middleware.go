@@ -0,0 +1,10 @@
+package types
+
+// InputMiddleware transforma o Markdown recebido antes da conversão
+type InputMiddleware func(input string) string
+
+// OutputMiddleware transforma cada parte já convertida antes de ela ser
+// devolvida. total é o número de partes da mensagem. O conteúdo devolvido não
+// passa por nenhum escape nem por nova divisão, então a middleware deve
+// produzir texto válido no modo de saída e respeitar o tamanho máximo.
+type OutputMiddleware func(part MessagePart, total int) MessagePart
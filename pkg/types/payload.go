@@ -0,0 +1,112 @@
+package types
+
+// PayloadOptions pede à conversão o corpo pronto do sendMessage de cada parte
+// (MessagePart.Payload), para ser enviado por qualquer cliente HTTP
+type PayloadOptions struct {
+	ChatID int64 `json:"chat_id"`
+	// MessageThreadID é o tópico do fórum ou do chat, se houver
+	MessageThreadID int `json:"message_thread_id"`
+	// ReplyToMessageID faz a primeira parte responder a essa mensagem
+	ReplyToMessageID int `json:"reply_to_message_id"`
+	// DisableNotification envia todas as partes sem som
+	DisableNotification bool `json:"disable_notification"`
+	// ProtectContent impede que as partes sejam encaminhadas ou salvas
+	ProtectContent bool `json:"protect_content"`
+	// LinkPreview é copiado para todas as partes. A pré-visualização escolhida
+	// com Config.LinkPreview tem prioridade sobre IsDisabled e URL.
+	LinkPreview *LinkPreviewOptions `json:"link_preview"`
+	// ReplyMarkup vai na última parte, abaixo da mensagem inteira, quando o
+	// Markdown não traz o seu próprio teclado (MessagePart.ReplyMarkup). Pode
+	// ser qualquer valor que vire o JSON de um teclado da Bot API.
+	ReplyMarkup any `json:"-"`
+}
+
+// SendMessagePayload é o corpo do método sendMessage da Bot API
+type SendMessagePayload struct {
+	ChatID              int64               `json:"chat_id"`
+	MessageThreadID     int                 `json:"message_thread_id,omitempty"`
+	Text                string              `json:"text"`
+	ParseMode           string              `json:"parse_mode,omitempty"`
+	LinkPreviewOptions  *LinkPreviewOptions `json:"link_preview_options,omitempty"`
+	ReplyParameters     *ReplyParameters    `json:"reply_parameters,omitempty"`
+	ReplyMarkup         any                 `json:"reply_markup,omitempty"`
+	DisableNotification bool                `json:"disable_notification,omitempty"`
+	ProtectContent      bool                `json:"protect_content,omitempty"`
+}
+
+// LinkPreviewOptions é o link_preview_options da Bot API
+type LinkPreviewOptions struct {
+	IsDisabled       bool   `json:"is_disabled,omitempty"`
+	URL              string `json:"url,omitempty"`
+	PreferSmallMedia bool   `json:"prefer_small_media,omitempty"`
+	PreferLargeMedia bool   `json:"prefer_large_media,omitempty"`
+	ShowAboveText    bool   `json:"show_above_text,omitempty"`
+}
+
+// ReplyParameters é o reply_parameters da Bot API
+type ReplyParameters struct {
+	MessageID int `json:"message_id"`
+	// Se a mensagem respondida for apagada, envia mesmo assim
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply,omitempty"`
+}
+
+// BuildPayloads preenche o Payload de cada parte com o texto e o parse_mode
+// da parte e as opções informadas
+func BuildPayloads(parts []MessagePart, options *PayloadOptions) {
+	for i := range parts {
+		payload := &SendMessagePayload{
+			ChatID:          options.ChatID,
+			MessageThreadID: options.MessageThreadID,
+			Text:            parts[i].Content,
+			ParseMode:       parts[i].ParseMode,
+
+			DisableNotification: options.DisableNotification,
+			ProtectContent:      options.ProtectContent,
+		}
+		payload.LinkPreviewOptions = mergeLinkPreview(options.LinkPreview, parts[i].LinkPreview)
+		if i == 0 && options.ReplyToMessageID != 0 {
+			payload.ReplyParameters = &ReplyParameters{
+				MessageID:                options.ReplyToMessageID,
+				AllowSendingWithoutReply: true,
+			}
+		}
+		if i == len(parts)-1 {
+			// O teclado escrito no próprio Markdown tem prioridade
+			if parts[i].ReplyMarkup != nil {
+				payload.ReplyMarkup = parts[i].ReplyMarkup
+			} else {
+				payload.ReplyMarkup = options.ReplyMarkup
+			}
+		}
+		parts[i].Payload = payload
+	}
+}
+
+// mergeLinkPreview junta as opções de aparência do payload com a
+// pré-visualização escolhida para a parte
+func mergeLinkPreview(options, part *LinkPreviewOptions) *LinkPreviewOptions {
+	if part == nil {
+		if options == nil {
+			return nil
+		}
+		preview := *options
+		return &preview
+	}
+	if part.IsDisabled {
+		return &LinkPreviewOptions{IsDisabled: true}
+	}
+	preview := LinkPreviewOptions{}
+	if options != nil {
+		preview = *options
+	}
+	preview.IsDisabled = false
+	preview.URL = part.URL
+	return &preview
+}
+
+// WithPayload faz a conversão gerar o corpo do sendMessage de cada parte
+func WithPayload(options PayloadOptions) Option {
+	return func(c *Config) {
+		c.Payload = &options
+	}
+}
@@ -0,0 +1,199 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LinkPreviewMode define qual link ganha a pré-visualização do Telegram
+// quando a mensagem é dividida em partes
+type LinkPreviewMode int
+
+const (
+	// LinkPreviewDefault deixa o Telegram decidir: cada parte mostra a
+	// pré-visualização do seu primeiro link
+	LinkPreviewDefault LinkPreviewMode = iota
+	// LinkPreviewDisabled desliga a pré-visualização em todas as partes
+	LinkPreviewDisabled
+	// LinkPreviewFirstLink mostra só a pré-visualização do primeiro link do
+	// documento, na parte em que ele aparece
+	LinkPreviewFirstLink
+	// LinkPreviewURL mostra a pré-visualização de Config.LinkPreviewURL, na
+	// parte em que ela aparece ou, se não aparecer, na primeira
+	LinkPreviewURL
+)
+
+func (m LinkPreviewMode) MarshalText() ([]byte, error) {
+	switch m {
+	case LinkPreviewDefault:
+		return []byte("default"), nil
+	case LinkPreviewDisabled:
+		return []byte("disabled"), nil
+	case LinkPreviewFirstLink:
+		return []byte("first_link"), nil
+	case LinkPreviewURL:
+		return []byte("url"), nil
+	}
+	return nil, fmt.Errorf("unknown link preview mode %d", int(m))
+}
+
+func (m *LinkPreviewMode) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "default":
+		*m = LinkPreviewDefault
+	case "disabled":
+		*m = LinkPreviewDisabled
+	case "first_link":
+		*m = LinkPreviewFirstLink
+	case "url":
+		*m = LinkPreviewURL
+	default:
+		return fmt.Errorf("unknown link preview mode %q (expected default, disabled, first_link or url)", text)
+	}
+	return nil
+}
+
+// WithLinkPreview define qual link ganha a pré-visualização
+func WithLinkPreview(mode LinkPreviewMode) Option {
+	return func(c *Config) {
+		c.LinkPreview = mode
+	}
+}
+
+// WithLinkPreviewURL mostra a pré-visualização da URL informada
+// (LinkPreviewURL)
+func WithLinkPreviewURL(url string) Option {
+	return func(c *Config) {
+		c.LinkPreview = LinkPreviewURL
+		c.LinkPreviewURL = url
+	}
+}
+
+// Só links http e https ganham pré-visualização. Na saída em MarkdownV2 a URL
+// pode ter caracteres escapados, e um ) sem escape fecha o link.
+var previewURLPattern = regexp.MustCompile(`https?://(?:\\.|[^\s\\)])+`)
+
+// ApplyLinkPreview escolhe a pré-visualização de cada parte conforme o modo e
+// a registra em MessagePart.LinkPreview. No modo padrão as partes não são
+// alteradas.
+func ApplyLinkPreview(parts []MessagePart, config *Config) {
+	if config.LinkPreview == LinkPreviewDefault || len(parts) == 0 {
+		return
+	}
+
+	chosen, url := -1, ""
+	switch config.LinkPreview {
+	case LinkPreviewFirstLink:
+		for i := range parts {
+			if found := firstPreviewURL(parts[i]); found != "" {
+				chosen, url = i, found
+				break
+			}
+		}
+	case LinkPreviewURL:
+		chosen, url = 0, config.LinkPreviewURL
+		for i := range parts {
+			if containsPreviewURL(parts[i], url) {
+				chosen = i
+				break
+			}
+		}
+	}
+
+	for i := range parts {
+		if i == chosen {
+			parts[i].LinkPreview = &LinkPreviewOptions{URL: url}
+		} else {
+			parts[i].LinkPreview = &LinkPreviewOptions{IsDisabled: true}
+		}
+	}
+}
+
+// firstPreviewURL devolve a primeira URL http ou https do texto da parte
+func firstPreviewURL(part MessagePart) string {
+	if urls := previewURLs(part); len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+func containsPreviewURL(part MessagePart, url string) bool {
+	for _, match := range previewURLs(part) {
+		if match == url {
+			return true
+		}
+	}
+	return false
+}
+
+// previewURLs devolve as URLs http e https do texto da parte, na ordem. URLs
+// dentro de código inline ou blocos de código não viram links no Telegram e
+// por isso ficam de fora.
+func previewURLs(part MessagePart) []string {
+	text := part.Content
+	if part.ParseMode == ParseModeMarkdownV2 {
+		text = stripMarkdownV2Code(text)
+	}
+	var urls []string
+	for _, match := range previewURLPattern.FindAllString(text, -1) {
+		if part.ParseMode == ParseModeMarkdownV2 {
+			match = unescapeMarkdownV2(match)
+		}
+		// Pontuação no fim pertence à frase, não à URL
+		urls = append(urls, strings.TrimRight(match, ".,;:!?'\""))
+	}
+	return urls
+}
+
+// stripMarkdownV2Code troca o código inline e os blocos de código do texto em
+// MarkdownV2 por um espaço. Fora do código, \X é um caractere escapado; dentro
+// dele só ` e \ são escapados.
+func stripMarkdownV2Code(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			b.WriteString(text[i : i+2])
+			i++
+		case text[i] == '`':
+			fence := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				fence = "```"
+			}
+			i = codeEnd(text, i+len(fence), fence) - 1
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+// codeEnd devolve a posição logo após o fence que fecha o código iniciado em
+// start, ou o fim do texto se ele não for fechado
+func codeEnd(text string, start int, fence string) int {
+	for i := start; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], fence) {
+			return i + len(fence)
+		}
+	}
+	return len(text)
+}
+
+func unescapeMarkdownV2(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
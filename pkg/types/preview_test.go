@@ -0,0 +1,86 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPreviewURLs(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		parseMode string
+		want      []string
+	}{
+		{"plain links", "veja https://a.com e http://b.com/x", ParseModeMarkdownV2, []string{"https://a.com", "http://b.com/x"}},
+		{"escaped characters", `https://a\.com/a\_b\.`, ParseModeMarkdownV2, []string{"https://a.com/a_b"}},
+		{"markdown link", `[site](https://a.com/x\)y) fim`, ParseModeMarkdownV2, []string{"https://a.com/x)y"}},
+		{"trailing punctuation", "em https://a.com, e https://b.com!", "", []string{"https://a.com", "https://b.com"}},
+		{"other schemes", "ftp://a.com tg://resolve", ParseModeMarkdownV2, nil},
+		{"inline code", "`https://code.com` e https://a.com", ParseModeMarkdownV2, []string{"https://a.com"}},
+		{"code block", "```\nhttps://code.com\n```\nhttps://a.com", ParseModeMarkdownV2, []string{"https://a.com"}},
+		{"escaped backtick in code", "`a \\` https://code.com` https://a.com", ParseModeMarkdownV2, []string{"https://a.com"}},
+		{"escaped backtick outside code", "\\` https://a.com \\`", ParseModeMarkdownV2, []string{"https://a.com"}},
+		{"unclosed code", "https://a.com `https://code.com", ParseModeMarkdownV2, []string{"https://a.com"}},
+		{"plain text keeps code", "`https://a.com`", "", []string{"https://a.com`"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := previewURLs(MessagePart{Content: tt.content, ParseMode: tt.parseMode})
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("previewURLs(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLinkPreview(t *testing.T) {
+	newParts := func() []MessagePart {
+		return []MessagePart{
+			{Content: "sem link `https://code.com`", ParseMode: ParseModeMarkdownV2},
+			{Content: "https://a.com e https://b.com", ParseMode: ParseModeMarkdownV2},
+			{Content: "https://b.com", ParseMode: ParseModeMarkdownV2},
+		}
+	}
+	// Cada parte vira "-" sem pré-visualização, "off" desligada ou a URL
+	describe := func(parts []MessagePart) string {
+		var out []string
+		for _, part := range parts {
+			switch {
+			case part.LinkPreview == nil:
+				out = append(out, "-")
+			case part.LinkPreview.IsDisabled:
+				out = append(out, "off")
+			default:
+				out = append(out, part.LinkPreview.URL)
+			}
+		}
+		return fmt.Sprint(out)
+	}
+
+	tests := []struct {
+		name string
+		mode LinkPreviewMode
+		url  string
+		want string
+	}{
+		{"default", LinkPreviewDefault, "", "[- - -]"},
+		{"disabled", LinkPreviewDisabled, "", "[off off off]"},
+		{"first link", LinkPreviewFirstLink, "", "[off https://a.com off]"},
+		{"url in a later part", LinkPreviewURL, "https://b.com", "[off https://b.com off]"},
+		{"url not in the text", LinkPreviewURL, "https://c.com", "[https://c.com off off]"},
+		{"url only inside code", LinkPreviewURL, "https://code.com", "[https://code.com off off]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LinkPreview = tt.mode
+			config.LinkPreviewURL = tt.url
+			parts := newParts()
+			ApplyLinkPreview(parts, config)
+			if got := describe(parts); got != tt.want {
+				t.Errorf("previews = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
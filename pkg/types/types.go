@@ -1,12 +1,53 @@
 package types
 
+import "time"
+
 type MessagePart struct {
 	Part    int    `json:"part"`
 	Content string `json:"content"`
+	// ParseMode é o parse_mode da Bot API para Content: "MarkdownV2", ou vazio
+	// para texto simples
+	ParseMode string `json:"parse_mode,omitempty"`
+	// LinkPreview é a pré-visualização escolhida para a parte: a URL
+	// mostrada ou IsDisabled. Fica vazio no modo LinkPreviewDefault.
+	LinkPreview *LinkPreviewOptions `json:"link_preview,omitempty"`
+	// ReplyMarkup é o teclado inline extraído do Markdown (ver
+	// WithButtonExtraction). Só a última parte o recebe.
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	// Payload é o corpo pronto do sendMessage, gerado quando a configuração
+	// tem Payload (ver WithPayload)
+	Payload *SendMessagePayload `json:"payload,omitempty"`
 }
 
 type MessageResponse struct {
-	MessageID  string        `json:"message_id"`
-	TotalParts int           `json:"total_parts"`
-	Parts      []MessagePart `json:"parts"`
+	MessageID   string        `json:"message_id"`
+	TotalParts  int           `json:"total_parts"`
+	Parts       []MessagePart `json:"parts"`
+	Diagnostics []Diagnostic  `json:"diagnostics,omitempty"`
+	Stats       *Stats        `json:"stats,omitempty"`
+}
+
+// Stats resume o que aconteceu durante uma conversão. Só é preenchido quando
+// a configuração pede, com WithStats. Tamanhos são contados em caracteres.
+type Stats struct {
+	InputSize int `json:"input_size"`
+	// Tamanho de cada parte renderizada, na ordem das partes
+	PartSizes []int `json:"part_sizes"`
+	// Número de blocos por tipo ("text", "code", "table", ...)
+	Blocks map[string]int `json:"blocks"`
+	// Caracteres escapados na saída
+	EscapedChars int `json:"escaped_chars"`
+	// Divisões feitas no meio de um bloco maior que o limite de tamanho
+	ForcedSplits int        `json:"forced_splits"`
+	Stages       StageTimes `json:"stages"`
+}
+
+// StageTimes guarda o tempo gasto em cada etapa da conversão. No JSON os
+// tempos vão em nanossegundos, como indica o sufixo _ns.
+type StageTimes struct {
+	InputSplit  time.Duration `json:"input_split_ns"`
+	Tokenize    time.Duration `json:"tokenize_ns"`
+	Render      time.Duration `json:"render_ns"`
+	OutputSplit time.Duration `json:"output_split_ns"`
+	Total       time.Duration `json:"total_ns"`
 }
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStageTimesJSON(t *testing.T) {
+	stages := StageTimes{
+		InputSplit:  time.Microsecond,
+		Tokenize:    2 * time.Microsecond,
+		Render:      3 * time.Microsecond,
+		OutputSplit: 4 * time.Microsecond,
+		Total:       10 * time.Microsecond,
+	}
+	data, err := json.Marshal(stages)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"input_split_ns":1000,"tokenize_ns":2000,"render_ns":3000,"output_split_ns":4000,"total_ns":10000}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sshturbo/GoTeleMD/internal"
+)
+
+// Validate verifica os limites de cada campo e as restrições entre eles.
+// Todos os problemas encontrados são devolvidos juntos, em um único erro do
+// tipo ErrInvalidConfig.
+func (c *Config) Validate() error {
+	var problems []error
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.SafetyLevel < internal.SAFETYLEVELNONE || c.SafetyLevel > internal.SAFETYLEVELSTRICT {
+		add("safety_level", "must be 0 (none), 1 (basic) or 2 (strict), got %d", c.SafetyLevel)
+	}
+
+	blockTypes := make([]BlockType, 0, len(c.BlockSafetyLevels))
+	for blockType := range c.BlockSafetyLevels {
+		blockTypes = append(blockTypes, blockType)
+	}
+	sort.Slice(blockTypes, func(i, j int) bool { return blockTypes[i] < blockTypes[j] })
+	for _, blockType := range blockTypes {
+		if level := c.BlockSafetyLevels[blockType]; level < internal.SAFETYLEVELNONE || level > internal.SAFETYLEVELSTRICT {
+			add("block_safety_levels", "level for %s blocks must be 0 (none), 1 (basic) or 2 (strict), got %d", blockType, level)
+		}
+	}
+
+	// Zero usa o limite do Telegram
+	if c.MaxMessageLength != 0 && (c.MaxMessageLength < internal.MinMessageLength || c.MaxMessageLength > internal.TelegramMaxLength) {
+		add("max_message_length", "must be between %d and %d (or 0 for the Telegram limit), got %d; %d characters of each part are reserved for escapes",
+			internal.MinMessageLength, internal.TelegramMaxLength, c.MaxMessageLength, internal.SplitSafetyMargin)
+	}
+
+	if c.NumWorkers < 0 {
+		add("num_workers", "must be 0 (one worker per CPU) or more, got %d", c.NumWorkers)
+	}
+	if c.WorkerQueueSize < 1 {
+		add("worker_queue_size", "must be at least 1, got %d", c.WorkerQueueSize)
+	}
+	if c.MaxConcurrentParts < 1 {
+		add("max_concurrent_parts", "must be at least 1, got %d", c.MaxConcurrentParts)
+	}
+
+	if _, err := c.OutputMode.MarshalText(); err != nil {
+		add("output_mode", "%v", err)
+	}
+
+	// O MarkdownV2 só aceita escape de caracteres com código entre 1 e 126
+	for i, char := range c.CustomEscapeChars {
+		if utf8.RuneCountInString(char) != 1 || char[0] < 1 || char[0] > 126 {
+			add("custom_escape_chars", "entry %d must be a single ASCII character (codes 1 to 126), got %q", i, char)
+		}
+	}
+
+	if p := c.LinkPolicy; p != nil {
+		if _, err := p.OnDisallowed.MarshalText(); err != nil {
+			add("link_policy.on_disallowed", "%v", err)
+		}
+		for i, scheme := range p.AllowedSchemes {
+			if scheme == "" || strings.ContainsAny(scheme, ":/ ") {
+				add("link_policy.allowed_schemes", "entry %d must be a bare scheme such as \"https\", got %q", i, scheme)
+			}
+		}
+	}
+
+	if _, err := c.LinkPreview.MarshalText(); err != nil {
+		add("link_preview", "%v", err)
+	}
+	if c.LinkPreview == LinkPreviewURL {
+		if u, err := url.Parse(c.LinkPreviewURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("link_preview_url", "must be an http or https URL when link_preview is url, got %q", c.LinkPreviewURL)
+		}
+	}
+
+	if p := c.Payload; p != nil {
+		if p.ChatID == 0 {
+			add("payload.chat_id", "must be set")
+		}
+		if p.MessageThreadID < 0 {
+			add("payload.message_thread_id", "must be >= 0, got %d", p.MessageThreadID)
+		}
+		if p.ReplyToMessageID < 0 {
+			add("payload.reply_to_message_id", "must be >= 0, got %d", p.ReplyToMessageID)
+		}
+		if p.LinkPreview != nil && p.LinkPreview.PreferSmallMedia && p.LinkPreview.PreferLargeMedia {
+			add("payload.link_preview", "prefer_small_media and prefer_large_media cannot both be set")
+		}
+	}
+
+	for i, block := range c.CustomBlocks {
+		if block.Pattern == nil {
+			add("custom_blocks", "entry %d has no pattern", i)
+		}
+	}
+
+	for i, ext := range c.InlineExtensions {
+		if ext.Pattern == nil || ext.Render == nil {
+			add("inline_extensions", "extension %d (%q) needs both a pattern and a render function", i, ext.Name)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return NewError(ErrInvalidConfig, "invalid configuration", errors.Join(problems...))
+}
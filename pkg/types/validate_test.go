@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"default", func(*Config) {}, ""},
+		{"zero max length", func(c *Config) { c.MaxMessageLength = 0 }, ""},
+		{"zero workers", func(c *Config) { c.NumWorkers = 0 }, ""},
+		{"nil link policy", func(c *Config) { c.LinkPolicy = nil }, ""},
+		{"safety level", func(c *Config) { c.SafetyLevel = 3 }, "safety_level"},
+		{"max length too small", func(c *Config) { c.MaxMessageLength = 100 }, "max_message_length"},
+		{"max length too large", func(c *Config) { c.MaxMessageLength = 5000 }, "max_message_length"},
+		{"negative workers", func(c *Config) { c.NumWorkers = -1 }, "num_workers"},
+		{"queue size", func(c *Config) { c.WorkerQueueSize = 0 }, "worker_queue_size"},
+		{"concurrent parts", func(c *Config) { c.MaxConcurrentParts = 0 }, "max_concurrent_parts"},
+		{"block level", func(c *Config) { c.BlockSafetyLevels = map[BlockType]int{BlockQuote: 3} }, "block_safety_levels: level for quote blocks"},
+		{"output mode", func(c *Config) { c.OutputMode = OutputMode(9) }, "output_mode"},
+		{"escape char", func(c *Config) { c.CustomEscapeChars = []string{"@", "ab"} }, "custom_escape_chars: entry 1"},
+		{"non-ASCII escape char", func(c *Config) { c.CustomEscapeChars = []string{"é"} }, "custom_escape_chars: entry 0"},
+		{"link action", func(c *Config) { c.LinkPolicy = &LinkPolicy{OnDisallowed: LinkAction(9)} }, "link_policy.on_disallowed"},
+		{"link scheme", func(c *Config) { c.LinkPolicy = &LinkPolicy{AllowedSchemes: []string{"https://"}} }, "link_policy.allowed_schemes"},
+		{"custom block", func(c *Config) { c.CustomBlocks = []CustomBlock{{}} }, "custom_blocks: entry 0"},
+		{"inline extension", func(c *Config) {
+			c.InlineExtensions = []InlineExtension{{Name: "x", Pattern: regexp.MustCompile("x")}}
+		}, `inline_extensions: extension 0 ("x")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want ErrInvalidConfig mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	config := DefaultConfig()
+	config.SafetyLevel = -1
+	config.WorkerQueueSize = 0
+	config.CustomEscapeChars = []string{"", "ab"}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want an error")
+	}
+	var configErr *Error
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Validate() = %T, want *Error", err)
+	}
+	joined, ok := configErr.Err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("wrapped error %T does not hold a list of problems", configErr.Err)
+	}
+	if got := len(joined.Unwrap()); got != 4 {
+		t.Errorf("Validate() reported %d problems, want 4: %v", got, err)
+	}
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotelemd.json")
+	if err := os.WriteFile(path, []byte(`{"max_message_length": 10}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "max_message_length") {
+		t.Errorf("LoadConfig() error = %v, want an invalid max_message_length", err)
+	}
+}
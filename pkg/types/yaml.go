@@ -0,0 +1,173 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine é uma linha útil do arquivo, já sem comentários
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML lê o subconjunto de YAML aceito por LoadYAML. Valores simples
+// ficam como string, listas como []any e mapas como map[string]any, no mesmo
+// formato produzido pela leitura do JSON.
+func parseYAML(data string) (map[string]any, error) {
+	var lines []yamlLine
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(line) - len(text), text: text})
+	}
+
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	values, rest, err := parseYAMLMapping(lines, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", rest[0].number)
+	}
+	return values, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, indent int) (map[string]any, []yamlLine, error) {
+	values := make(map[string]any)
+	for len(lines) > 0 {
+		line := lines[0]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		if isYAMLListItem(line.text) {
+			return nil, nil, fmt.Errorf("line %d: unexpected list item", line.number)
+		}
+
+		key, value, ok := strings.Cut(line.text, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		if _, exists := values[key]; exists {
+			return nil, nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		lines = lines[1:]
+
+		if value = strings.TrimSpace(value); value != "" {
+			parsed, err := parseYAMLValue(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			values[key] = parsed
+			continue
+		}
+
+		// Sem valor na mesma linha: o conteúdo vem nas linhas indentadas
+		// seguintes, ou a chave fica vazia
+		if len(lines) == 0 || lines[0].indent <= indent {
+			values[key] = ""
+			continue
+		}
+		var err error
+		if isYAMLListItem(lines[0].text) {
+			values[key], lines, err = parseYAMLList(lines, lines[0].indent)
+		} else {
+			values[key], lines, err = parseYAMLMapping(lines, lines[0].indent)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return values, lines, nil
+}
+
+func parseYAMLList(lines []yamlLine, indent int) ([]any, []yamlLine, error) {
+	items := []any{}
+	for len(lines) > 0 && lines[0].indent == indent && isYAMLListItem(lines[0].text) {
+		item, err := parseYAMLScalar(strings.TrimSpace(strings.TrimPrefix(lines[0].text, "-")))
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lines[0].number, err)
+		}
+		items = append(items, item)
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && lines[0].indent > indent {
+		return nil, nil, fmt.Errorf("line %d: unexpected indentation", lines[0].number)
+	}
+	return items, lines, nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseYAMLValue lê o valor de uma chave: uma lista [a, b] ou um valor simples
+func parseYAMLValue(value string) (any, error) {
+	if !strings.HasPrefix(value, "[") {
+		return parseYAMLScalar(value)
+	}
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("unterminated list %s", value)
+	}
+	items := []any{}
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	if inner == "" {
+		return items, nil
+	}
+	for _, item := range strings.Split(inner, ",") {
+		parsed, err := parseYAMLScalar(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, parsed)
+	}
+	return items, nil
+}
+
+func parseYAMLScalar(value string) (string, error) {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return strconv.Unquote(value)
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	if strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "'") {
+		return "", fmt.Errorf("unterminated string %s", value)
+	}
+	return value, nil
+}
+
+// stripYAMLComment remove o comentário da linha. # só inicia um comentário no
+// começo da linha ou depois de um espaço, e nunca dentro de aspas.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
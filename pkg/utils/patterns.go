@@ -2,6 +2,10 @@ package utils
 
 import "regexp"
 
+// LinkURLPattern casa a URL de um link: aceita um nível de parênteses
+// balanceados e parênteses escapados
+const LinkURLPattern = `(?:[^()\\\n]|\\.|\([^()\n]*\))*`
+
 var (
 	TitlePattern       = regexp.MustCompile(`(?m)^(#{1,6})\s*(.+)$`)
 	BoldPattern        = regexp.MustCompile(`(\*\*)(.*?)\*\*|(__)(.*?)__|(\*)([^*\n]+?)(\*)`)
@@ -11,7 +15,10 @@ var (
 	OrderedListPattern = regexp.MustCompile(`(?m)^\s*\d+\.\s+(.+)$`)
 	BlockquotePattern  = regexp.MustCompile(`(?m)^>\s*(.+)$`)
 	InlineCodePattern  = regexp.MustCompile("`([^`\n]+)`")
-	LinkPattern        = regexp.MustCompile(`\[(.*?)\]\((.*?)\)`)
+	LinkPattern        = regexp.MustCompile(`\[(.*?)\]\((` + LinkURLPattern + `)\)`)
+	MentionPattern     = regexp.MustCompile(`\[([^\]\n]*)\]\(tg://user\?id=([^)\s]*)\)`)
+	CustomEmojiPattern = regexp.MustCompile(`!\[([^\]\n]*)\]\(tg://emoji\?id=([^)\s]*)\)`)
 	TableLinePattern   = regexp.MustCompile(`(?m)^\|(.+)\|$`)
 	SeparatorLine      = regexp.MustCompile(`^\s*[:\-\| ]+\s*$`)
+	EscapedCharPattern = regexp.MustCompile(`\\([[:punct:]])`)
 )